@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// actionInputsEnv carries the action inputs as JSON. Composite actions do not
+// export INPUT_* variables to their steps, so action.yml passes
+// toJSON(inputs) through this variable instead.
+const actionInputsEnv = "COLLECTTODO_INPUTS"
+
+// actionInputs collects the GitHub Action inputs from the INPUT_* variables
+// and from COLLECTTODO_INPUTS, keyed by lower-case input name. Empty inputs
+// are left out so they do not override other settings.
+func actionInputs() (map[string]string, error) {
+	inputs := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "INPUT_") && value != "" {
+			inputs[normalizeInputName(strings.TrimPrefix(name, "INPUT_"))] = value
+		}
+	}
+	if raw := os.Getenv(actionInputsEnv); raw != "" {
+		var fromJSON map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &fromJSON); err != nil {
+			return nil, fmt.Errorf("%s: %w", actionInputsEnv, err)
+		}
+		for name, value := range fromJSON {
+			if s := fmt.Sprint(value); value != nil && s != "" {
+				inputs[normalizeInputName(name)] = s
+			}
+		}
+	}
+	return inputs, nil
+}
+
+func normalizeInputName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+}
+
+// splitLines splits a multi-line input, one entry per non-empty line
+func splitLines(value string) []string {
+	var items []string
+	for _, line := range strings.Split(value, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// applyActionInputs overlays the action inputs on c. List inputs that may
+// contain commas (notification targets) take one entry per line. Inputs the
+// tool does not know are ignored, since GitHub passes every declared input.
+func applyActionInputs(c *Config, inputs map[string]string) error {
+	for name, value := range inputs {
+		switch name {
+		case "root_dir", "roots":
+			c.Roots = splitList(value)
+		case "blacklist", "excludes":
+			c.Excludes = append(c.Excludes, splitList(value)...)
+		case "whitelist", "includes":
+			c.Includes = append(c.Includes, splitList(value)...)
+		case "keywords":
+			c.Keywords = splitList(value)
+		case "tag_pattern":
+			c.TagPattern = value
+		case "multiline":
+			c.Multiline = value == "true"
+		case "match_budget":
+			c.Limits.MatchBudget = value
+		case "ignore_case":
+			c.IgnoreCase = value == "true"
+		case "include_generated":
+			c.IncludeGenerated = value == "true"
+		case "include_md_tasks":
+			c.IncludeMDTasks = value == "true"
+		case "raw":
+			c.Raw = value == "true"
+		case "read_only":
+			c.ReadOnly = value == "true"
+		case "preset":
+			c.Preset = value
+		case "pattern", "patterns":
+			c.Patterns = splitLines(value)
+		case "tracker":
+			c.Outputs.Tracker = value
+		case "manifest":
+			c.Outputs.Manifest = value
+		case "badge":
+			c.Outputs.Badge = value
+		case "forge":
+			c.Outputs.Forge = value
+		case "repo_url":
+			c.Outputs.RepoURL = value
+		case "issue_url_template":
+			c.Outputs.IssueURLTemplate = value
+		case "notify":
+			c.Outputs.Notify = append(c.Outputs.Notify, splitLines(value)...)
+		case "routes":
+			c.Outputs.Routes = append(c.Outputs.Routes, splitLines(value)...)
+		case "escalate":
+			c.Policies.Escalate = append(c.Policies.Escalate, splitLines(value)...)
+		case "tag_aliases":
+			// One alias=tag per line
+			if c.TagAliases == nil {
+				c.TagAliases = make(map[string]string)
+			}
+			for _, line := range splitLines(value) {
+				alias, tag, ok := strings.Cut(line, "=")
+				if !ok {
+					return fmt.Errorf("input tag_aliases: %q must be alias=tag", line)
+				}
+				c.TagAliases[strings.TrimSpace(alias)] = strings.TrimSpace(tag)
+			}
+		case "reminders":
+			c.Policies.Reminders = append(c.Policies.Reminders, splitLines(value)...)
+		case "grace_period":
+			c.Policies.GracePeriod = value
+		case "pin_permalinks":
+			c.Outputs.PinPermalinks = value == "true"
+		case "commit_status":
+			c.Outputs.CommitStatus = value == "true"
+		case "fail_on_overdue":
+			c.Policies.FailOnOverdue = value == "true"
+		case "fail_on_expired":
+			c.Policies.FailOnExpired = value == "true"
+		case "fail_on_severity":
+			c.Policies.FailOnSeverity = value
+		case "valid_tags":
+			c.ValidTags = splitList(value)
+		case "strict_tags":
+			c.Policies.StrictTags = value == "true"
+		case "no_net_increase":
+			c.Policies.NoNetIncrease = value == "true"
+		case "base":
+			c.Policies.Base = value
+		case "max_file_size", "max_total_bytes", "max_line_length", "max_items_per_file", "max_items_per_dir", "stale_days", "context", "badge_yellow", "badge_red":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("input %s: %q is not a number", name, value)
+			}
+			switch name {
+			case "max_file_size":
+				c.Limits.MaxFileSize = n
+			case "max_total_bytes":
+				c.Limits.MaxTotalBytes = n
+			case "max_line_length":
+				c.Limits.MaxLineLength = n
+			case "max_items_per_file":
+				c.Limits.MaxItemsPerFile = n
+			case "max_items_per_dir":
+				c.Limits.MaxItemsPerDir = n
+			case "context":
+				c.Context = n
+			case "badge_yellow":
+				c.Outputs.BadgeYellow = n
+			case "badge_red":
+				c.Outputs.BadgeRed = n
+			default:
+				c.Policies.StaleDays = n
+			}
+		}
+	}
+	return nil
+}
+
+// setActionOutputs appends outputs to the file named by $GITHUB_OUTPUT, so
+// later workflow steps can read them as steps.<id>.outputs.<name>. It does
+// nothing outside GitHub Actions.
+func setActionOutputs(outputs map[string]string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, name := range names {
+		if _, err := fmt.Fprintf(f, "%s=%s\n", name, outputs[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
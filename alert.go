@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// alerter pages a team about a single TODO
+type alerter interface {
+	Alert(t TodoItem) error
+}
+
+// escalation sends newly introduced TODOs with one of its tags to an alerter
+type escalation struct {
+	tags    map[string]bool
+	alerter alerter
+}
+
+// parseEscalation parses tag1,tag2=provider[:key]. Without a key the
+// provider's key is read from PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY.
+func parseEscalation(spec string) (escalation, error) {
+	var e escalation
+	tags, target, err := parseTagTarget(spec)
+	if err != nil {
+		return e, err
+	}
+	e.tags = tags
+
+	provider, key, _ := strings.Cut(target, ":")
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch strings.ToLower(provider) {
+	case "pagerduty":
+		if key == "" {
+			key = os.Getenv("PAGERDUTY_ROUTING_KEY")
+		}
+		e.alerter = &pagerDutyAlerter{routingKey: key, client: client}
+	case "opsgenie":
+		if key == "" {
+			key = os.Getenv("OPSGENIE_API_KEY")
+		}
+		e.alerter = &opsgenieAlerter{apiKey: key, client: client}
+	default:
+		return e, fmt.Errorf("unknown alerting provider %q", provider)
+	}
+	if key == "" {
+		return e, fmt.Errorf("no key configured for %s", provider)
+	}
+	return e, nil
+}
+
+// buildEscalations creates the alerting rules listed in the configuration
+func buildEscalations(cfg Config) ([]escalation, error) {
+	var escalations []escalation
+	for _, spec := range cfg.Policies.Escalate {
+		e, err := parseEscalation(spec)
+		if err != nil {
+			return nil, err
+		}
+		escalations = append(escalations, e)
+	}
+	return escalations, nil
+}
+
+// escalate alerts on every new TODO whose tag is mapped to a provider
+func escalate(escalations []escalation, d digest) error {
+	for _, t := range d.New {
+		for _, e := range escalations {
+			if !e.tags[t.Tag] {
+				continue
+			}
+			if err := e.alerter.Alert(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// alertKey lets the providers deduplicate repeated alerts for the same TODO
+func alertKey(t TodoItem) string {
+	return "collecttodo-" + t.ID
+}
+
+func alertSummary(t TodoItem) string {
+	return fmt.Sprintf("New %s at %s:%d: %s", t.Label(), t.File, t.Line, t.Description)
+}
+
+type pagerDutyAlerter struct {
+	routingKey string
+	client     *http.Client
+}
+
+func (a *pagerDutyAlerter) Alert(t TodoItem) error {
+	event := map[string]interface{}{
+		"routing_key":  a.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    alertKey(t),
+		"payload": map[string]interface{}{
+			"summary":  alertSummary(t),
+			"source":   t.File,
+			"severity": "critical",
+			"custom_details": map[string]interface{}{
+				"tag":         t.Tag,
+				"description": t.Description,
+				"file":        t.File,
+				"line":        t.Line,
+			},
+		},
+	}
+	return sendJSON(a.client, http.MethodPost, pagerDutyEventsURL, event, nil)
+}
+
+type opsgenieAlerter struct {
+	apiKey string
+	client *http.Client
+}
+
+func (a *opsgenieAlerter) Alert(t TodoItem) error {
+	alert := map[string]interface{}{
+		"message":     alertSummary(t),
+		"alias":       alertKey(t),
+		"description": t.Description,
+		"priority":    "P1",
+		"tags":        []string{"collecttodo", t.Tag},
+		"details": map[string]string{
+			"file": t.File,
+			"line": fmt.Sprint(t.Line),
+		},
+	}
+	return sendJSON(a.client, http.MethodPost, opsgenieAlertsURL, alert, func(req *http.Request) {
+		req.Header.Set("Authorization", "GenieKey "+a.apiKey)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GitHub Actions turns workflow commands printed by a step, such as
+//
+//	::warning file=main.go,line=12,col=4,title=TODO[perf]::Cache the result
+//
+// into annotations shown on the lines of the pull request's changed files
+
+// annotationCommands maps severities to workflow commands
+var annotationCommands = map[string]string{"info": "notice", "warning": "warning", "error": "error"}
+
+// escapeAnnotationData escapes the message of a workflow command
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a property value of a workflow command
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// formatGitHubAnnotations prints one workflow command per TODO, at its
+// severity, and one warning per scan warning, for --format=gh-annotations
+func formatGitHubAnnotations(r Report) (string, error) {
+	var b strings.Builder
+	for _, t := range r.Todos {
+		command := annotationCommands[t.Severity]
+		if command == "" {
+			command = "warning"
+		}
+		props := fmt.Sprintf("file=%s,line=%d", escapeAnnotationProperty(repoPath(t.File)), t.Line)
+		if t.RuneColumn > 0 {
+			props += fmt.Sprintf(",col=%d", t.RuneColumn)
+		}
+		props += ",title=" + escapeAnnotationProperty(t.Label())
+		b.WriteString(fmt.Sprintf("::%s %s::%s\n", command, props, escapeAnnotationData(t.Label()+" "+t.Description)))
+	}
+	for _, w := range r.Warnings {
+		b.WriteString(fmt.Sprintf("::warning file=%s,title=Scan warning::%s\n", escapeAnnotationProperty(w.Path), escapeAnnotationData(w.Message)))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mentionPattern finds @username mentions in a description. The @ must start
+// a word, so e-mail addresses are not mistaken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(,;])@([A-Za-z0-9][\w.-]*[\w])`)
+
+// parseAssignees returns the users mentioned in a description, in order and
+// without duplicates
+func parseAssignees(description string) []string {
+	var assignees []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(description, -1) {
+		if !containsString(assignees, m[1]) {
+			assignees = append(assignees, m[1])
+		}
+	}
+	return assignees
+}
+
+// formatMarkdownByAssignee renders the TODO summary with a section per
+// assignee. An item mentioning several people is listed under each of them,
+// and items nobody is assigned to come last.
+func formatMarkdownByAssignee(todos []TodoItem, opts markdownOptions) string {
+	var b strings.Builder
+	b.WriteString("# TODO Summary\n\n")
+	if len(todos) == 0 {
+		b.WriteString("No TODOs found.\n")
+		return b.String()
+	}
+	byAssignee := make(map[string][]TodoItem)
+	var unassigned []TodoItem
+	for _, t := range todos {
+		if len(t.Assignees) == 0 {
+			unassigned = append(unassigned, t)
+		}
+		for _, a := range t.Assignees {
+			byAssignee[a] = append(byAssignee[a], t)
+		}
+	}
+	assignees := make([]string, 0, len(byAssignee))
+	for a := range byAssignee {
+		assignees = append(assignees, a)
+	}
+	sort.Slice(assignees, func(i, j int) bool { return strings.ToLower(assignees[i]) < strings.ToLower(assignees[j]) })
+	section := func(title string, items []TodoItem) {
+		b.WriteString(fmt.Sprintf("## %s\n\n", title))
+		sortByUrgency(items)
+		b.WriteString(formatMarkdownItems(items, true, opts))
+		b.WriteString("\n")
+	}
+	for _, a := range assignees {
+		section("@"+a, byAssignee[a])
+	}
+	if len(unassigned) > 0 {
+		section("Unassigned", unassigned)
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Atom 1.0 types, limited to what feed readers need
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string        `xml:"id"`
+	Title    string        `xml:"title"`
+	Updated  string        `xml:"updated"`
+	Author   *atomPerson   `xml:"author,omitempty"`
+	Link     *atomLink     `xml:"link,omitempty"`
+	Category []atomTerm    `xml:"category"`
+	Content  atomPlainText `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomPlainText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// formatAtomFeed renders the TODOs first seen on the day of the run as an
+// Atom feed, for --format=atom. Readers remember the entries they have
+// seen, so publishing the feed after every run shows new TODOs as they
+// appear.
+func formatAtomFeed(r Report) (string, error) {
+	// The feed's ID only depends on the scanned directories, so that it is
+	// the same from one run to the next
+	sum := sha256.Sum256([]byte(strings.Join(r.Roots, "\n")))
+	feed := atomFeed{
+		ID:      "urn:collecttodo:feed:" + hex.EncodeToString(sum[:6]),
+		Title:   "New TODOs",
+		Updated: r.Generated,
+		Author:  atomPerson{Name: "CollectTODO"},
+	}
+	for _, t := range r.Todos {
+		if t.Date != r.Now {
+			continue
+		}
+		entry := atomEntry{
+			ID:      "urn:collecttodo:todo:" + t.ID,
+			Title:   t.Label() + ": " + t.Description,
+			Updated: r.Generated,
+			Content: atomPlainText{Type: "text", Text: fmt.Sprintf("%s %s (%s:%d)", t.Label(), t.Description, repoPath(t.File), t.Line)},
+		}
+		if t.Tag != "" {
+			entry.Category = []atomTerm{{Term: t.Tag}}
+		}
+		if t.Author != "" {
+			entry.Author = &atomPerson{Name: t.Author}
+		}
+		if t.Permalink != "" {
+			entry.Link = &atomLink{Href: t.Permalink}
+		} else if r.Link != nil {
+			entry.Link = &atomLink{Href: r.Link(t.File, t.Line)}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
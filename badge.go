@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+)
+
+// Default counts from which the badge turns yellow and red
+const (
+	defaultBadgeYellow = 50
+	defaultBadgeRed    = 200
+)
+
+// badge is a shields.io endpoint file, schema version 1, such as
+// {"schemaVersion":1,"label":"TODOs","message":"42","color":"yellow"}
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// newBadge colors the count green, yellow from yellow TODOs on, and red from
+// red TODOs on
+func newBadge(count, yellow, red int) badge {
+	color := "green"
+	if count >= red {
+		color = "red"
+	} else if count >= yellow {
+		color = "yellow"
+	}
+	return badge{SchemaVersion: 1, Label: "TODOs", Message: strconv.Itoa(count), Color: color}
+}
+
+// writeBadge writes the badge of the open TODOs to path
+func writeBadge(path string, count int, c OutputsConfig) error {
+	data, err := json.Marshal(newBadge(count, c.BadgeYellow, c.BadgeRed))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
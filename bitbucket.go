@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	bitbucketAPI      = "https://api.bitbucket.org/2.0"
+	bitbucketReportID = "collecttodo"
+	// Bitbucket accepts at most 100 annotations per request and 1000 per report
+	bitbucketAnnotationBatch = 100
+	bitbucketAnnotationLimit = 1000
+	bitbucketSummaryLimit    = 450
+)
+
+// bitbucketForge talks to Bitbucket Cloud using the variables provided by
+// Bitbucket Pipelines. Authentication uses BITBUCKET_ACCESS_TOKEN, or
+// BITBUCKET_USERNAME with BITBUCKET_APP_PASSWORD.
+type bitbucketForge struct {
+	apiURL    string
+	workspace string
+	repoSlug  string
+	commit    string
+	prID      string
+	token     string
+	username  string
+	password  string
+	client    *http.Client
+}
+
+func newBitbucketForge() (*bitbucketForge, error) {
+	f := &bitbucketForge{
+		apiURL:    os.Getenv("BITBUCKET_API_URL"),
+		workspace: os.Getenv("BITBUCKET_WORKSPACE"),
+		repoSlug:  os.Getenv("BITBUCKET_REPO_SLUG"),
+		commit:    os.Getenv("BITBUCKET_COMMIT"),
+		prID:      os.Getenv("BITBUCKET_PR_ID"),
+		token:     os.Getenv("BITBUCKET_ACCESS_TOKEN"),
+		username:  os.Getenv("BITBUCKET_USERNAME"),
+		password:  os.Getenv("BITBUCKET_APP_PASSWORD"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if f.apiURL == "" {
+		f.apiURL = bitbucketAPI
+	}
+	if f.workspace == "" || f.repoSlug == "" || f.commit == "" {
+		return nil, fmt.Errorf("BITBUCKET_WORKSPACE, BITBUCKET_REPO_SLUG and BITBUCKET_COMMIT must be set")
+	}
+	if f.token == "" && (f.username == "" || f.password == "") {
+		return nil, fmt.Errorf("set BITBUCKET_ACCESS_TOKEN or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD")
+	}
+	return f, nil
+}
+
+func (f *bitbucketForge) setAuth(req *http.Request) {
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+		return
+	}
+	req.SetBasicAuth(f.username, f.password)
+}
+
+func (f *bitbucketForge) repoURL() string {
+	return fmt.Sprintf("%s/repositories/%s/%s", f.apiURL, f.workspace, f.repoSlug)
+}
+
+func (f *bitbucketForge) Permalink(file string, line int) string {
+	return f.PermalinkAt(f.commit, file, line)
+}
+
+func (f *bitbucketForge) PermalinkAt(commit, file string, line int) string {
+	return fmt.Sprintf("https://bitbucket.org/%s/%s/src/%s/%s#lines-%d", f.workspace, f.repoSlug, commit, repoPath(file), line)
+}
+
+// PostSummary comments on the pull request; outside of a PR pipeline it does nothing
+func (f *bitbucketForge) PostSummary(markdown string) error {
+	if f.prID == "" {
+		return nil
+	}
+	body := map[string]interface{}{
+		"content": map[string]string{"raw": markdown},
+	}
+	return sendJSON(f.client, http.MethodPost, fmt.Sprintf("%s/pullrequests/%s/comments", f.repoURL(), f.prID), body, f.setAuth)
+}
+
+// PostAnnotations creates a code insights report for the commit with one
+// annotation per TODO
+func (f *bitbucketForge) PostAnnotations(todos []TodoItem) error {
+	reportURL := fmt.Sprintf("%s/commit/%s/reports/%s", f.repoURL(), f.commit, bitbucketReportID)
+	report := map[string]interface{}{
+		"title":       "TODO Summary",
+		"details":     fmt.Sprintf("%d TODOs found", len(todos)),
+		"report_type": "BUG",
+		"reporter":    "CollectTODO",
+		"result":      "PASSED",
+		"data": []map[string]interface{}{
+			{"title": "TODOs", "type": "NUMBER", "value": len(todos)},
+		},
+	}
+	if err := sendJSON(f.client, http.MethodPut, reportURL, report, f.setAuth); err != nil {
+		return err
+	}
+
+	if len(todos) > bitbucketAnnotationLimit {
+		todos = todos[:bitbucketAnnotationLimit]
+	}
+	for start := 0; start < len(todos); start += bitbucketAnnotationBatch {
+		end := start + bitbucketAnnotationBatch
+		if end > len(todos) {
+			end = len(todos)
+		}
+		var annotations []map[string]interface{}
+		for i, t := range todos[start:end] {
+			summary := fmt.Sprintf("%s: %s", t.Label(), t.Description)
+			summary = truncate(summary, bitbucketSummaryLimit)
+			annotations = append(annotations, map[string]interface{}{
+				"external_id":     fmt.Sprintf("todo-%d", start+i),
+				"annotation_type": "CODE_SMELL",
+				"severity":        "LOW",
+				"summary":         summary,
+				"path":            repoPath(t.File),
+				"line":            t.Line,
+			})
+		}
+		if err := sendJSON(f.client, http.MethodPost, reportURL+"/annotations", annotations, f.setAuth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Origin is the commit, path and line where a TODO was introduced
+type Origin struct {
+	Commit string `json:"commit"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+}
+
+// blameLine finds the commit that introduced a line of a file. Lines that are
+// not committed yet have no origin.
+func blameLine(file string, line int) (Origin, bool) {
+	n := strconv.Itoa(line)
+	out, err := exec.Command("git", "blame", "--porcelain", "-L", n+","+n, "--", file).Output()
+	if err != nil {
+		return Origin{}, false
+	}
+	var o Origin
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		text := scanner.Text()
+		if o.Commit == "" {
+			// <commit> <original line> <final line> <group size>
+			fields := strings.Fields(text)
+			if len(fields) < 3 || strings.Trim(fields[0], "0") == "" {
+				return Origin{}, false
+			}
+			o.Commit = fields[0]
+			o.Line, _ = strconv.Atoi(fields[1])
+			continue
+		}
+		if name, ok := strings.CutPrefix(text, "filename "); ok {
+			o.File = name
+			break
+		}
+	}
+	return o, o.Commit != "" && o.File != ""
+}
+
+// pinPermalinks records where every TODO without an origin was introduced
+// and, with a forge, its permalink at that commit, so that links keep
+// pointing at the right line after the file changes
+func pinPermalinks(todos []TodoItem, host forge) {
+	for i, t := range todos {
+		if t.Origin == nil {
+			o, ok := blameLine(t.File, t.Line)
+			if !ok {
+				continue
+			}
+			todos[i].Origin = &o
+		}
+		if host != nil && todos[i].Permalink == "" {
+			o := todos[i].Origin
+			todos[i].Permalink = host.PermalinkAt(o.Commit, o.File, o.Line)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runCheck scans the tree and enforces the policies like a summary run, but
+// prints nothing unless a policy fails, for git hooks and make targets
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	addPolicyFlags(fs)
+	filterExpr := fs.String("filter", "", "Only enforce policies on the TODOs matching a filter expression")
+	view := fs.String("view", "", "Only enforce policies on the TODOs of a view defined in the config file")
+	minPriority := fs.String("min-priority", "", "Only enforce policies on the TODOs of this priority or a more urgent one")
+	fs.Parse(args)
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	only, err := selectFilter(cfg, *view, *filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if only, err = withMinPriority(only, *minPriority); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --min-priority: %v\n", err)
+		os.Exit(1)
+	}
+	violations, err := check(cfg, time.Now().Format("2006-01-02"), only)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+	for _, v := range violations {
+		fmt.Printf("%s: %s\n", v.policy, v.message)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// check scans the tree and returns the policy violations. It leaves the
+// tree as it found it: the tracker is compared with but not updated, and
+// no manifest or badge is written. A temporary work tree of the base
+// revision is still checked out when a policy needs one.
+func check(cfg Config, now string, only filter) ([]policyViolation, error) {
+	cfg.keepTracker = true
+	cfg.Outputs.Manifest = ""
+	cfg.Outputs.Badge = ""
+
+	_, updated, _, err := scanAndTrack(cfg, now)
+	if err != nil {
+		return nil, err
+	}
+	violations, err := evaluatePolicies(cfg, updated, now, only)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policies: %w", err)
+	}
+	return violations, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// git runs a git command in the working directory
+func git(t *testing.T, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestCheckAgainstBaseRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	const now = "2026-01-02"
+	base := "package x\n// TODO[a]: one\n"
+	tests := []struct {
+		name string
+		// tracked commits a tracker with the base; otherwise check scans the
+		// base in a temporary work tree
+		tracked    bool
+		head       string
+		violations int
+	}{
+		{"scanned base, one TODO added", false, base + "// TODO[b]: two\n", 1},
+		{"scanned base, no TODO added", false, base, 0},
+		{"tracked base, one TODO added", true, base + "// TODO[b]: two\n", 1},
+		{"tracked base, one TODO resolved", true, "package x\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			c := DefaultConfig()
+			c.Policies.NoNetIncrease = true
+			c.Policies.Base = "HEAD"
+			// check must neither write the badge nor fail on it
+			c.Outputs.Badge = "badge.json"
+
+			if err := os.WriteFile("a.go", []byte(base), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.tracked {
+				todos := updateTodos(nil, []TodoItem{{File: "a.go", Line: 2, Tag: "a", Description: "one"}}, now)
+				if err := saveTracker(c.Outputs.Tracker, TodoTracker{Todos: todos}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			git(t, "init", "-q")
+			git(t, "add", "-A")
+			git(t, "commit", "-q", "-m", "base")
+			if err := os.WriteFile("a.go", []byte(tt.head), 0644); err != nil {
+				t.Fatal(err)
+			}
+			before, _ := os.ReadFile(c.Outputs.Tracker)
+
+			only, err := selectFilter(c, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations, err := check(c, now, only)
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if len(violations) != tt.violations {
+				t.Errorf("got %d violations %v, want %d", len(violations), violations, tt.violations)
+			}
+			if _, err := os.Stat("badge.json"); !os.IsNotExist(err) {
+				t.Error("check wrote the badge")
+			}
+			if after, _ := os.ReadFile(c.Outputs.Tracker); string(after) != string(before) {
+				t.Error("check updated the tracker")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// Checkstyle XML types, as read by reviewdog and Jenkins Warnings NG
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr,omitempty"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	// Source is collecttodo.<tag> for TODOs and collecttodo.scan.<kind>
+	// for scan warnings, so they can be filtered by rule
+	Source string `xml:"source,attr"`
+}
+
+// formatCheckstyleReport renders the TODOs as a checkstyle report, one file
+// element per file in path order, for --format=checkstyle
+func formatCheckstyleReport(r Report) (string, error) {
+	files := make(map[string][]checkstyleError)
+	for _, t := range r.Todos {
+		rule := t.Tag
+		if rule == "" {
+			rule = "untagged"
+		}
+		severity := t.Severity
+		if severity == "" {
+			severity = severityWarning
+		}
+		path := repoPath(t.File)
+		files[path] = append(files[path], checkstyleError{
+			Line:     t.Line,
+			Column:   t.RuneColumn,
+			Severity: severity,
+			Message:  t.Label() + ": " + t.Description,
+			Source:   "collecttodo." + rule,
+		})
+	}
+	for _, w := range r.Warnings {
+		files[w.Path] = append(files[w.Path], checkstyleError{
+			Severity: "warning",
+			Message:  w.Message,
+			Source:   "collecttodo.scan." + w.Kind,
+		})
+	}
+	report := checkstyleReport{Version: "8.0", Files: []checkstyleFile{}}
+	for name, errs := range files {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		report.Files = append(report.Files, checkstyleFile{Name: name, Errors: errs})
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Name < report.Files[j].Name })
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
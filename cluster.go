@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// descriptionWords returns the set of lower-case words of a description
+func descriptionWords(description string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[w] = true
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two word sets: the share of their words
+// they have in common, from 0 to 1
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// clusterTodos groups items whose descriptions are at least threshold
+// similar to the first item of a group, keeping the order of the items
+func clusterTodos(items []TodoItem, threshold float64) [][]TodoItem {
+	var clusters [][]TodoItem
+	var firsts []map[string]bool
+	for _, t := range items {
+		words := descriptionWords(t.Description)
+		joined := false
+		for i, first := range firsts {
+			if similarity(words, first) >= threshold {
+				clusters[i] = append(clusters[i], t)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			clusters = append(clusters, []TodoItem{t})
+			firsts = append(firsts, words)
+		}
+	}
+	return clusters
+}
+
+// representative returns the description most similar to the others of a
+// cluster
+func representative(cluster []TodoItem) string {
+	words := make([]map[string]bool, len(cluster))
+	for i, t := range cluster {
+		words[i] = descriptionWords(t.Description)
+	}
+	best, bestScore := 0, -1.0
+	for i := range cluster {
+		score := 0.0
+		for j := range cluster {
+			if i != j {
+				score += similarity(words[i], words[j])
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return cluster[best].Description
+}
+
+// formatMarkdownItems renders the items of a summary section, with their
+// labels when labeled is set. With opts.Cluster, items with similar
+// descriptions are shown as one entry with their count, and the items
+// themselves collapsed below it.
+func formatMarkdownItems(items []TodoItem, labeled bool, opts markdownOptions) string {
+	var b strings.Builder
+	item := func(t TodoItem) string {
+		if labeled {
+			return formatMarkdownItem(t, t.Label(), opts)
+		}
+		return formatMarkdownItem(t, "", opts)
+	}
+	if opts.Cluster <= 0 {
+		for _, t := range items {
+			b.WriteString(item(t))
+		}
+		return b.String()
+	}
+	for _, cluster := range clusterTodos(items, opts.Cluster) {
+		if len(cluster) == 1 {
+			b.WriteString(item(cluster[0]))
+			continue
+		}
+		oldest := cluster[0].Date
+		for _, t := range cluster {
+			if t.Date < oldest {
+				oldest = t.Date
+			}
+		}
+		b.WriteString(fmt.Sprintf("- **%d similar** (oldest %s): %s\n", len(cluster), oldest, linkReferences(representative(cluster), opts.IssueLink)))
+		b.WriteString(fmt.Sprintf("\n  <details><summary>%d items</summary>\n\n", len(cluster)))
+		for _, t := range cluster {
+			b.WriteString(indentLines(item(t), "  "))
+		}
+		b.WriteString("\n  </details>\n\n")
+	}
+	return b.String()
+}
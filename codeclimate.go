@@ -0,0 +1,59 @@
+package main
+
+import "encoding/json"
+
+// codeClimateIssue is an issue of the Code Climate spec, with the fields
+// GitLab Code Quality reads
+type codeClimateIssue struct {
+	Type        string   `json:"type"`
+	CheckName   string   `json:"check_name"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories"`
+	Severity    string   `json:"severity"`
+	// Fingerprint is the TODO's ID, so that GitLab compares the issues of
+	// the base and head branches by TODO and shows only new ones
+	Fingerprint string              `json:"fingerprint"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// codeClimateSeverities maps severities to Code Climate's
+var codeClimateSeverities = map[string]string{"info": "info", "warning": "minor", "error": "major"}
+
+// formatCodeClimateReport renders the TODOs as a Code Climate issue array,
+// the GitLab Code Quality report, for --format=codeclimate
+func formatCodeClimateReport(r Report) (string, error) {
+	issues := []codeClimateIssue{}
+	for _, t := range r.Todos {
+		rule := t.Tag
+		if rule == "" {
+			rule = "untagged"
+		}
+		severity := codeClimateSeverities[t.Severity]
+		if severity == "" {
+			severity = "minor"
+		}
+		issue := codeClimateIssue{
+			Type:        "issue",
+			CheckName:   "collecttodo/" + rule,
+			Description: t.Label() + ": " + t.Description,
+			Categories:  []string{"Clarity"},
+			Severity:    severity,
+			Fingerprint: t.ID,
+		}
+		issue.Location.Path = repoPath(t.File)
+		issue.Location.Lines.Begin = t.Line
+		issues = append(issues, issue)
+	}
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Locations searched for a CODEOWNERS file, in the order GitHub uses
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// codeowners maps repository paths to their owners. Later rules take
+// precedence over earlier ones, as in GitHub and Bitbucket.
+type codeowners struct {
+	rules []codeownersRule
+}
+
+// loadCodeowners reads the first CODEOWNERS file found in the standard locations
+func loadCodeowners() (codeowners, error) {
+	var co codeowners
+	for _, p := range codeownersPaths {
+		f, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return co, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Fields(line)
+			co.rules = append(co.rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+		}
+		return co, scanner.Err()
+	}
+	return co, nil
+}
+
+// Owners returns the owners of a slash-separated, repository-relative path
+func (co codeowners) Owners(file string) []string {
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if matchCodeownersPattern(co.rules[i].pattern, file) {
+			return co.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// Emails returns the owners of a path that are e-mail addresses rather than
+// user or team handles
+func (co codeowners) Emails(file string) []string {
+	var emails []string
+	for _, owner := range co.Owners(file) {
+		if !strings.HasPrefix(owner, "@") && strings.Contains(owner, "@") {
+			emails = append(emails, owner)
+		}
+	}
+	return emails
+}
+
+// matchCodeownersPattern implements the subset of gitignore matching used by
+// CODEOWNERS files: anchored and unanchored patterns, directory patterns
+// and globs on single path segments
+func matchCodeownersPattern(pattern, file string) bool {
+	if pattern == "*" {
+		return true
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	segments := strings.Split(file, "/")
+	if !anchored {
+		// Unanchored patterns match any path segment, directories included
+		for i, seg := range segments {
+			if ok, _ := path.Match(pattern, seg); ok && (!dirOnly || i < len(segments)-1) {
+				return true
+			}
+		}
+		return false
+	}
+	// Anchored patterns match the path itself or any of its parent directories
+	for i := len(segments); i > 0; i-- {
+		if dirOnly && i == len(segments) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(segments[:i], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedTags returns the tags of the ownership registry, sorted
+func ownedTags(c Config) []string {
+	tags := make([]string, 0, len(c.Owners))
+	for tag := range c.Owners {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ownerOf returns the team owning a tag, or ""
+func ownerOf(c Config, tag string) string {
+	return c.Owners[tag].Team
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineClassifier finds the comments of a language, line by line. Languages
+// are added by registering a classifier for their extensions with
+// registerLineClassifier, usually from the init function of their own file;
+// commentSyntax covers the common cases.
+type LineClassifier interface {
+	// Comments returns the comments of a line as [start, end) offsets,
+	// given the state left open by the previous line, and returns the state
+	// it leaves open for the next
+	Comments(line string, open commentState) ([][2]int, commentState)
+}
+
+// commentSyntax describes the comments of a language: the markers of line
+// comments, the delimiters of block comments, and the quotes of string
+// literals, inside which markers do not start a comment. Python docstrings
+// count as block comments.
+type commentSyntax struct {
+	line   []string
+	block  [][2]string
+	quotes string
+	// multiline lists the quotes of string literals that may span lines,
+	// such as Go raw strings and JavaScript template literals
+	multiline string
+	// docstrings makes block delimiters start a comment only when they
+	// start the line; elsewhere, as in x = """...""", they quote a string
+	docstrings bool
+	// chars skips character literals such as '"' and '\n'; other single
+	// quotes, such as Rust lifetimes, are left alone
+	chars bool
+}
+
+// commentState is what a line leaves open for the next: a block comment or
+// a string literal, with its closing delimiter
+type commentState struct {
+	close   string
+	literal bool
+}
+
+// inComment reports whether a block comment is open
+func (s commentState) inComment() bool {
+	return s.close != "" && !s.literal
+}
+
+var (
+	cStyle    = &commentSyntax{line: []string{"//"}, block: [][2]string{{"/*", "*/"}}, quotes: "\"`", multiline: "`", chars: true}
+	jsStyle   = &commentSyntax{line: []string{"//"}, block: [][2]string{{"/*", "*/"}}, quotes: "\"'`", multiline: "`"}
+	hashStyle = &commentSyntax{line: []string{"#"}, quotes: "\"'"}
+	dashStyle = &commentSyntax{line: []string{"--"}, block: [][2]string{{"/*", "*/"}}, quotes: "'"}
+	python    = &commentSyntax{line: []string{"#"}, block: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, quotes: "\"'", docstrings: true}
+	markup    = &commentSyntax{block: [][2]string{{"<!--", "-->"}}}
+	cssStyle  = &commentSyntax{block: [][2]string{{"/*", "*/"}}, quotes: "\"'"}
+	iniStyle  = &commentSyntax{line: []string{";", "#"}}
+	texStyle  = &commentSyntax{line: []string{"%"}}
+	phpStyle  = &commentSyntax{line: []string{"//", "#"}, block: [][2]string{{"/*", "*/"}}, quotes: "\"'"}
+	luaStyle  = &commentSyntax{line: []string{"--"}, block: [][2]string{{"--[[", "]]"}}, quotes: "\"'"}
+	haskell   = &commentSyntax{line: []string{"--"}, block: [][2]string{{"{-", "-}"}}, quotes: "\""}
+	terraform = &commentSyntax{line: []string{"#", "//"}, block: [][2]string{{"/*", "*/"}}, quotes: "\""}
+)
+
+// lineClassifiers maps lower-case extensions, and base names of files
+// without one, to the classifier of their language
+var lineClassifiers = map[string]LineClassifier{
+	".go": cStyle, ".c": cStyle, ".h": cStyle, ".cc": cStyle, ".cpp": cStyle, ".hpp": cStyle,
+	".java": cStyle, ".cs": cStyle, ".swift": cStyle, ".kt": cStyle, ".kts": cStyle,
+	".scala": cStyle, ".rs": cStyle, ".dart": cStyle, ".m": cStyle, ".groovy": cStyle,
+	".gradle": cStyle, ".proto": cStyle, ".zig": cStyle,
+	".js": jsStyle, ".jsx": jsStyle, ".mjs": jsStyle, ".cjs": jsStyle, ".ts": jsStyle, ".tsx": jsStyle,
+	".scss": jsStyle, ".less": jsStyle,
+	".css": cssStyle,
+	".py":  python, ".rb": hashStyle, ".sh": hashStyle, ".bash": hashStyle, ".zsh": hashStyle,
+	".yml": hashStyle, ".yaml": hashStyle, ".toml": hashStyle, ".r": hashStyle, ".pl": hashStyle,
+	".pm": hashStyle, ".ps1": hashStyle, ".cmake": hashStyle, ".conf": hashStyle, ".nix": hashStyle,
+	".ex": hashStyle, ".exs": hashStyle, ".jl": hashStyle,
+	"makefile": hashStyle, "dockerfile": hashStyle, "cmakelists.txt": hashStyle,
+	".tf": terraform, ".hcl": terraform,
+	".php": phpStyle,
+	".sql": dashStyle,
+	".lua": luaStyle,
+	".hs":  haskell, ".elm": haskell,
+	".html": markup, ".htm": markup, ".xml": markup, ".svg": markup, ".vue": markup,
+	".svelte": markup, ".md": markup, ".markdown": markup,
+	".ini": iniStyle, ".cfg": iniStyle,
+	".tex": texStyle, ".erl": texStyle,
+}
+
+// registerLineClassifier makes c the classifier of files with the given
+// extensions, such as ".lua", or base names, such as "makefile", replacing
+// any classifier registered before
+func registerLineClassifier(c LineClassifier, keys ...string) {
+	for _, key := range keys {
+		lineClassifiers[strings.ToLower(key)] = c
+	}
+}
+
+// lineClassifierFor returns the classifier of a file, or nil when the
+// language is unknown and every line is matched as a whole
+func lineClassifierFor(path string) LineClassifier {
+	base := strings.ToLower(filepath.Base(path))
+	if c, ok := lineClassifiers[base]; ok {
+		return c
+	}
+	return lineClassifiers[filepath.Ext(base)]
+}
+
+// Comments finds the comments of a line; text inside string literals is
+// never part of a comment
+func (cs *commentSyntax) Comments(line string, open commentState) ([][2]int, commentState) {
+	var regions [][2]int
+	i := 0
+	if open.close != "" {
+		j := strings.Index(line, open.close)
+		if j < 0 {
+			if open.literal {
+				return nil, open
+			}
+			return [][2]int{{0, len(line)}}, open
+		}
+		if !open.literal {
+			regions = append(regions, [2]int{0, j})
+		}
+		i = j + len(open.close)
+	}
+next:
+	for ; i < len(line); i++ {
+		for _, b := range cs.block {
+			if strings.HasPrefix(line[i:], b[0]) {
+				literal := cs.docstrings && strings.TrimSpace(line[:i]) != ""
+				start := i + len(b[0])
+				j := strings.Index(line[start:], b[1])
+				if j < 0 {
+					if literal {
+						return regions, commentState{close: b[1], literal: true}
+					}
+					return append(regions, [2]int{i, len(line)}), commentState{close: b[1]}
+				}
+				if !literal {
+					regions = append(regions, [2]int{i, start + j})
+				}
+				i = start + j + len(b[1]) - 1
+				continue next
+			}
+		}
+		for _, marker := range cs.line {
+			if strings.HasPrefix(line[i:], marker) {
+				return append(regions, [2]int{i, len(line)}), commentState{}
+			}
+		}
+		if cs.chars && line[i] == '\'' {
+			if end := charLiteralEnd(line, i); end > 0 {
+				i = end
+				continue
+			}
+		}
+		if strings.IndexByte(cs.quotes, line[i]) >= 0 {
+			// Skip the string literal, honoring backslash escapes
+			quote := line[i]
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) && strings.IndexByte(cs.multiline, quote) >= 0 {
+				return regions, commentState{close: string(quote), literal: true}
+			}
+		}
+	}
+	return regions, commentState{}
+}
+
+// charLiteralEnd returns the offset of the quote closing the character
+// literal opened at line[start], such as 'x', '"' or '\u00e9', or 0 when the
+// quote does not open one
+func charLiteralEnd(line string, start int) int {
+	i := start + 1
+	if i >= len(line) {
+		return 0
+	}
+	if line[i] != '\\' {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		if end := i + size; end < len(line) && line[end] == '\'' {
+			return end
+		}
+		return 0
+	}
+	// Escapes are short: \n, \x41, \u00e9, \u{1F600}, \U0001F600
+	for j := i + 2; j < len(line) && j <= i+12; j++ {
+		if line[j] == '\'' {
+			return j
+		}
+	}
+	return 0
+}
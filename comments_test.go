@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// commentTexts runs a classifier over lines and returns the comment text of
+// each line, regions joined with |
+func commentTexts(c LineClassifier, lines []string) []string {
+	var texts []string
+	var open commentState
+	for _, line := range lines {
+		var regions [][2]int
+		regions, open = c.Comments(line, open)
+		var parts []string
+		for _, r := range regions {
+			parts = append(parts, line[r[0]:r[1]])
+		}
+		texts = append(texts, strings.Join(parts, "|"))
+	}
+	return texts
+}
+
+func TestCommentSyntaxes(t *testing.T) {
+	tests := []struct {
+		name   string
+		syntax *commentSyntax
+		lines  []string
+		want   []string
+	}{
+		// C style: Go, C, Java, Rust...
+		{"c/line", cStyle, []string{"x := 1 // TODO[a]: b"}, []string{"// TODO[a]: b"}},
+		{"c/block spanning lines", cStyle, []string{"a /* start", "middle", "end */ b", "c"}, []string{"/* start", "middle", "end ", ""}},
+		{"c/blocks on one line", cStyle, []string{"a /* x */ b /* y */"}, []string{"/* x |/* y "}},
+		{"c/marker in string", cStyle, []string{`s := "// no" // yes`}, []string{"// yes"}},
+		{"c/escaped quote", cStyle, []string{`s := "a\" // no" // yes`}, []string{"// yes"}},
+		{"c/rune literal of a quote", cStyle, []string{`r := '"' // TODO[a]: b`}, []string{"// TODO[a]: b"}},
+		{"c/char literals", cStyle, []string{`c = '\'', d = '/' // x`, `e = '\x41' + '\u00e9' // y`, `f := '日' // z`}, []string{"// x", "// y", "// z"}},
+		{"c/rust lifetime", cStyle, []string{`fn f<'a>(s: &'a str) -> &'a str { s } // x`}, []string{"// x"}},
+		{"c/raw string spanning lines", cStyle, []string{"s := `first // no", "/* still no`  // yes"}, []string{"", "// yes"}},
+
+		// JavaScript and TypeScript
+		{"js/single quotes", jsStyle, []string{"s = '// no' // yes"}, []string{"// yes"}},
+		{"js/template literal spanning lines", jsStyle, []string{"t = `a", "// no` // yes"}, []string{"", "// yes"}},
+		{"js/block spanning lines", jsStyle, []string{"/**", " * TODO[a]: b", " */ f()"}, []string{"/**", " * TODO[a]: b", " "}},
+
+		// Shell, Ruby, YAML...
+		{"hash/line", hashStyle, []string{"# TODO[a]: b"}, []string{"# TODO[a]: b"}},
+		{"hash/marker in strings", hashStyle, []string{`echo "# no" '#no' # yes`}, []string{"# yes"}},
+		{"hash/no block comments", hashStyle, []string{"x /* not */"}, []string{""}},
+
+		// SQL
+		{"dash/line", dashStyle, []string{"SELECT '--no' -- yes"}, []string{"-- yes"}},
+		{"dash/block spanning lines", dashStyle, []string{"/* a", "b */ SELECT 1"}, []string{"/* a", "b "}},
+
+		// Python
+		{"python/line", python, []string{`s = "# no"  # yes`}, []string{"# yes"}},
+		{"python/docstring spanning lines", python, []string{`"""Doc`, "TODO[x]: y", `"""`, "x = 1"}, []string{`"""Doc`, "TODO[x]: y", "", ""}},
+		{"python/one-line docstring", python, []string{`    """One line"""`}, []string{`"""One line`}},
+		{"python/single-quoted docstring", python, []string{"'''a", "b'''"}, []string{"'''a", "b"}},
+		{"python/multiline string is not a comment", python, []string{`x = """`, "# no", `"""  # yes`}, []string{"", "", "# yes"}},
+
+		// HTML, XML, markdown
+		{"markup/block spanning lines", markup, []string{"<!-- a", "b -->", "<p>c</p>"}, []string{"<!-- a", "b ", ""}},
+		{"markup/block after element", markup, []string{`<a title="x"><!-- y -->`}, []string{"<!-- y "}},
+
+		// CSS
+		{"css/marker in string", cssStyle, []string{`a { content: "/* no */"; } /* yes */`}, []string{"/* yes "}},
+		{"css/no line comments", cssStyle, []string{"a { b: url(//x) }"}, []string{""}},
+
+		// INI
+		{"ini/semicolon", iniStyle, []string{"; a"}, []string{"; a"}},
+		{"ini/hash", iniStyle, []string{"key = v # b"}, []string{"# b"}},
+
+		// TeX and Erlang
+		{"tex/line", texStyle, []string{"x % c"}, []string{"% c"}},
+
+		// PHP
+		{"php/all markers", phpStyle, []string{`$a = "#no"; # yes`, "// y", "/* z */"}, []string{"# yes", "// y", "/* z "}},
+
+		// Lua
+		{"lua/block spanning lines", luaStyle, []string{"--[[ a", "b ]] x = 1"}, []string{"--[[ a", "b "}},
+		{"lua/marker in string", luaStyle, []string{`x = "--no" -- yes`}, []string{"-- yes"}},
+
+		// Haskell and Elm
+		{"haskell/block spanning lines", haskell, []string{"{- a", "b -} x"}, []string{"{- a", "b "}},
+		{"haskell/marker in string", haskell, []string{`s = "--no" -- yes`}, []string{"-- yes"}},
+
+		// Terraform and HCL
+		{"terraform/markers", terraform, []string{`a = "#no" # yes`, "// c", "/* d", "e */"}, []string{"# yes", "// c", "/* d", "e "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commentTexts(tt.syntax, tt.lines)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d %q: got comment %q, want %q", i+1, tt.lines[i], got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLineClassifierFor(t *testing.T) {
+	tests := []struct {
+		path string
+		want LineClassifier
+	}{
+		{"main.go", cStyle},
+		{"src/App.TSX", jsStyle},
+		{"scripts/tool.py", python},
+		{"Makefile", hashStyle},
+		{"build/Dockerfile", hashStyle},
+		{"notes.txt", nil},
+	}
+	for _, tt := range tests {
+		if got := lineClassifierFor(tt.path); got != tt.want {
+			t.Errorf("lineClassifierFor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterLineClassifier(t *testing.T) {
+	erlang := &commentSyntax{line: []string{"%"}, quotes: "\""}
+	saved, ok := lineClassifiers[".hrl"]
+	defer func() {
+		if ok {
+			lineClassifiers[".hrl"] = saved
+		} else {
+			delete(lineClassifiers, ".hrl")
+		}
+	}()
+	registerLineClassifier(erlang, ".HRL")
+	if got := lineClassifierFor("x.hrl"); got != erlang {
+		t.Fatalf("lineClassifierFor(x.hrl) = %v, want the registered classifier", got)
+	}
+}
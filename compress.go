@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Trackers and outputs named *.gz are gzip-compressed, which makes the
+// trackers of large monorepos several times smaller in CI caches
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// errZstd is returned for zstd files, which the standard library cannot
+// read or write. Only gzip is supported: a zstd codec would be the tool's
+// first dependency outside the standard library.
+var errZstd = errors.New("zstd compression is not supported, only gzip; name the file .gz, and convert an existing one with zstd -dc FILE.zst | gzip > FILE.gz")
+
+// compressed reports whether a file is written gzip-compressed
+func compressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// checkCompression rejects paths asking for a compression that is not
+// supported
+func checkCompression(path string) error {
+	if strings.HasSuffix(path, ".zst") || strings.HasSuffix(path, ".zstd") {
+		return errZstd
+	}
+	return nil
+}
+
+// closers closes a (de)compressor, then the file under it
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var err error
+	for _, c := range cs {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+type readCloser struct {
+	io.Reader
+	closers
+}
+
+type writeCloser struct {
+	io.Writer
+	closers
+}
+
+// openCompressed opens a file for reading, decompressing it when it is
+// gzip-compressed, whatever its name
+func openCompressed(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{r, closers{r, f}}, nil
+}
+
+// decompress reads r, decompressing it when it is gzip-compressed
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, errZstd
+	}
+	return io.NopCloser(br), nil
+}
+
+// createCompressed creates a file for writing, compressing what is written
+// when its name ends with .gz; the file is complete once closed
+func createCompressed(path string) (io.WriteCloser, error) {
+	if err := checkCompression(path); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !compressed(path) {
+		return f, nil
+	}
+	zw := gzip.NewWriter(f)
+	return writeCloser{zw, closers{zw, f}}, nil
+}
+
+// writeCompressedFile is os.WriteFile, compressing .gz files
+func writeCompressedFile(path string, data []byte) error {
+	w, err := createCompressed(path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
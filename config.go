@@ -0,0 +1,678 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKeyword     = "TODO"
+	defaultPattern     = `TODO\[(\w+)\](?:\[[^\]]*\])*: (.+)`
+	defaultTagPattern  = `[\p{L}\p{M}\p{N}_]+`
+	defaultMaxFileSize = 500 * 1024 // 500 KB
+	defaultMatchBudget = "10s"
+	defaultTrackerPath = "todo_tracker.json"
+	defaultConfigPath  = ".collecttodo.json"
+	envPrefix          = "COLLECTTODO_"
+)
+
+// Paths that are not scanned unless included explicitly
+var builtinExcludes = []string{
+	".action-tmp", // Folder itself when executing Github Action
+}
+
+// Config holds everything that controls a run. It is a plain value: it is
+// assembled from the defaults, the config file, the environment and the
+// command line flags, in that order, and passed to the constructors that
+// need it.
+type Config struct {
+	// Roots are the directories to scan
+	Roots []string `json:"roots"`
+	// Patterns are tried in order on every line; each must capture the tag
+	// and the description, in that order or in groups named tag and
+	// description
+	Patterns []string `json:"patterns"`
+	// Keywords replace TODO in the default pattern, e.g. TODO, FIXME, HACK,
+	// XXX and NOTE; custom patterns are not affected
+	Keywords []string `json:"keywords,omitempty"`
+	// TagPattern is the regular expression a tag matches in the built-in
+	// patterns; by default letters and digits of any script, and _
+	TagPattern string `json:"tag_pattern,omitempty"`
+	// Multiline appends the indented comment lines following a TODO to its
+	// description
+	Multiline bool `json:"multiline,omitempty"`
+	// IncludeGenerated scans generated files, which are recognized by a
+	// "Code generated ... DO NOT EDIT" or @generated marker near the top
+	// and skipped by default
+	IncludeGenerated bool `json:"include_generated,omitempty"`
+	// IncludeMDTasks collects the unchecked tasks of markdown task lists,
+	// - [ ] like this, as TODOs tagged markdown
+	IncludeMDTasks bool `json:"include_md_tasks,omitempty"`
+	// Context is the number of lines of code kept before and after each
+	// TODO and shown with it in the summary
+	Context int `json:"context,omitempty"`
+	// IgnoreCase matches the keywords of the built-in patterns in any case,
+	// e.g. todo[x]: and Todo[x]:, while items keep the upper-case keyword
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+	// Raw matches the patterns against whole lines rather than only the
+	// comments of files in known languages
+	Raw bool `json:"raw,omitempty"`
+	// ReadOnly guarantees that nothing is written to disk: the tracker is
+	// not updated, and commands that have to write fail instead
+	ReadOnly bool `json:"read_only,omitempty"`
+	// keepTracker compares the scan with the tracker without updating it,
+	// for check, which otherwise writes what it needs to like a summary run
+	keepTracker bool
+	// Excludes lists base names, extensions and paths to ignore
+	Excludes []string `json:"excludes"`
+	// Includes lists base names, extensions and paths to scan even when
+	// excluded
+	Includes []string `json:"includes"`
+	// Extensions, when set, are the only file types scanned; base names
+	// such as Makefile cover files without an extension
+	Extensions []string `json:"extensions,omitempty"`
+	// Preset names comma-separated bundles of extensions and excludes for
+	// common stacks: go, node, python, rust, java or monorepo
+	Preset string `json:"preset,omitempty"`
+	// Shard restricts the scan to one k/n partition of the files, for
+	// splitting a scan across parallel CI jobs
+	Shard string `json:"shard,omitempty"`
+	// Views names filter expressions, selected with --view in summaries,
+	// notifications and policies
+	Views map[string]string `json:"views,omitempty"`
+	// Suppress lists rules keeping the items they match out of reports and
+	// policies, and CountSuppressed notes their number in the summary
+	Suppress        []SuppressRule `json:"suppress,omitempty"`
+	CountSuppressed bool           `json:"count_suppressed,omitempty"`
+	// TagAliases maps spellings of a tag to the canonical one, e.g. perf
+	// and PERF to performance; items are stored with the canonical tag
+	TagAliases map[string]string `json:"tag_aliases,omitempty"`
+	// Tickets recognize the tickets referenced by descriptions; tracker keys
+	// such as JIRA-456 are recognized when it is empty
+	Tickets []TicketRecognizer `json:"tickets,omitempty"`
+	// Severities maps keywords to info, warning or error, over the defaults
+	// (NOTE is info, TODO warning, FIXME, HACK, XXX and BUG error)
+	Severities map[string]string `json:"severities,omitempty"`
+	// ValidTags, when set, are the only tags TODOs should use; others are
+	// reported, and fail the run with policies.strict_tags
+	ValidTags []string `json:"valid_tags,omitempty"`
+	// Owners maps tags to the team responsible for their TODOs
+	Owners map[string]TagOwner `json:"owners,omitempty"`
+	// Translation detects the language of descriptions and translates them
+	// into one language for the reports
+	Translation TranslationConfig `json:"translation,omitempty"`
+	// Summary adds an executive summary, written by a command, to reports
+	Summary SummaryConfig `json:"summary,omitempty"`
+	// Embeddings enables semantic search with search --semantic
+	Embeddings EmbeddingsConfig `json:"embeddings,omitempty"`
+	// Hooks are commands run before and after every scan
+	Hooks    HooksConfig    `json:"hooks,omitempty"`
+	Limits   LimitsConfig   `json:"limits"`
+	Outputs  OutputsConfig  `json:"outputs"`
+	Policies PoliciesConfig `json:"policies"`
+}
+
+// TagOwner is the team owning a tag, shown in reports, and where the tag's
+// TODOs are sent
+type TagOwner struct {
+	Team string `json:"team"`
+	// Contact is a provider:target that receives the tag's part of every
+	// digest, like a route
+	Contact string `json:"contact,omitempty"`
+	// Escalation lists age=provider:target steps, like reminders, that are
+	// notified in turn as the tag's TODOs get older, e.g. the team at 30d
+	// and its lead at 90d
+	Escalation []string `json:"escalation,omitempty"`
+}
+
+// LimitsConfig bounds the work done by a scan
+type LimitsConfig struct {
+	// MaxFileSize is the size in bytes above which files are skipped
+	MaxFileSize int `json:"max_file_size"`
+	// MaxTotalBytes stops a scan that would read more bytes in total;
+	// 0 is unlimited
+	MaxTotalBytes int `json:"max_total_bytes,omitempty"`
+	// MaxLineLength is the length in bytes above which lines are not
+	// matched, which bounds the time spent on any line; 0 is unlimited
+	MaxLineLength int `json:"max_line_length,omitempty"`
+	// MatchBudget is the time, such as 10s, after which matching a file
+	// stops; its TODOs found so far are kept and the file is reported
+	MatchBudget string `json:"match_budget,omitempty"`
+	// MaxItemsPerFile and MaxItemsPerDir stop collecting the TODOs of a
+	// file, or of the files directly in a directory, past that many, so that
+	// generated fixtures full of TODO strings do not dominate reports; 0 is
+	// unlimited
+	MaxItemsPerFile int `json:"max_items_per_file,omitempty"`
+	MaxItemsPerDir  int `json:"max_items_per_dir,omitempty"`
+}
+
+// OutputsConfig says where results go
+type OutputsConfig struct {
+	// Tracker is where first-seen dates are kept between runs
+	Tracker string `json:"tracker"`
+	// Forge is the code hosting service to publish the summary to
+	Forge string `json:"forge"`
+	// Notify lists provider:target digest destinations
+	Notify []string `json:"notify"`
+	// Routes lists tag1,tag2=provider:target digest destinations
+	Routes []string `json:"routes"`
+	// RepoURL is the repository #123 references link to, and
+	// IssueURLTemplate the URL of other references with {id} in place of
+	// the reference, e.g. https://example.atlassian.net/browse/{id}
+	RepoURL          string `json:"repo_url,omitempty"`
+	IssueURLTemplate string `json:"issue_url_template,omitempty"`
+	// Manifest, when set, is where each run records the files it scanned,
+	// skipped and failed on and the hash of its configuration
+	Manifest string `json:"manifest,omitempty"`
+	// PinPermalinks links every TODO at the commit that introduced it, so
+	// links in long-lived reports keep pointing at the right line
+	PinPermalinks bool `json:"pin_permalinks,omitempty"`
+	// CommitStatus sets the collecttodo/policy commit status on GitHub, so
+	// required statuses can gate merges on the policies
+	CommitStatus bool `json:"commit_status,omitempty"`
+	// Badge, when set, is where a shields.io endpoint file with the number
+	// of open TODOs is written; the badge turns yellow from BadgeYellow
+	// TODOs on and red from BadgeRed on
+	Badge       string `json:"badge,omitempty"`
+	BadgeYellow int    `json:"badge_yellow,omitempty"`
+	BadgeRed    int    `json:"badge_red,omitempty"`
+}
+
+// PoliciesConfig says how TODOs are followed up over time
+type PoliciesConfig struct {
+	// StaleDays is the age after which open TODOs are reported as stale
+	StaleDays int `json:"stale_days"`
+	// Escalate lists tag1,tag2=provider[:key] alerting rules
+	Escalate []string `json:"escalate"`
+	// Reminders lists age=provider:target reminder rules
+	Reminders []string `json:"reminders"`
+	// GracePeriod is an age such as 14d below which TODOs are left out of
+	// the age-based policies: stale listings and reminders
+	GracePeriod string `json:"grace_period,omitempty"`
+	// NoNetIncrease fails a change that adds more TODOs than it removes
+	NoNetIncrease bool `json:"no_net_increase,omitempty"`
+	// FailOnOverdue fails the run while a TODO is past its due date
+	FailOnOverdue bool `json:"fail_on_overdue,omitempty"`
+	// FailOnExpired fails the run while a TODO is past its expiry date
+	FailOnExpired bool `json:"fail_on_expired,omitempty"`
+	// FailOnSeverity fails the run while a TODO is at least this severe:
+	// info, warning or error
+	FailOnSeverity string `json:"fail_on_severity,omitempty"`
+	// StrictTags fails the run while a TODO uses a tag missing from
+	// valid_tags
+	StrictTags bool `json:"strict_tags,omitempty"`
+	// Base is the tracker file or git revision changes are compared with;
+	// it defaults to the target branch of the pull request
+	Base string `json:"base,omitempty"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		Roots:      []string{"."},
+		Patterns:   []string{defaultPattern},
+		Keywords:   []string{defaultKeyword},
+		TagPattern: defaultTagPattern,
+		Limits:     LimitsConfig{MaxFileSize: defaultMaxFileSize, MatchBudget: defaultMatchBudget},
+		Outputs:    OutputsConfig{Tracker: defaultTrackerPath, BadgeYellow: defaultBadgeYellow, BadgeRed: defaultBadgeRed},
+		Policies:   PoliciesConfig{StaleDays: 90},
+	}
+}
+
+// Option adjusts a Config; used with NewScanner
+type Option func(*Config)
+
+// WithRoots sets the directories to scan
+func WithRoots(roots ...string) Option {
+	return func(c *Config) { c.Roots = roots }
+}
+
+// WithExcludes adds entries to the ignore list
+func WithExcludes(entries ...string) Option {
+	return func(c *Config) { c.Excludes = append(c.Excludes, entries...) }
+}
+
+// WithIncludes adds entries that override the ignore list
+func WithIncludes(entries ...string) Option {
+	return func(c *Config) { c.Includes = append(c.Includes, entries...) }
+}
+
+// WithPatterns replaces the TODO patterns
+func WithPatterns(patterns ...string) Option {
+	return func(c *Config) { c.Patterns = patterns }
+}
+
+// WithMaxFileSize sets the size in bytes above which files are skipped
+func WithMaxFileSize(size int) Option {
+	return func(c *Config) { c.Limits.MaxFileSize = size }
+}
+
+// ScanOptions returns the options for scanning one of the configured roots
+func (c Config) ScanOptions(root string) ScanOptions {
+	opts := ScanOptions{Root: root, Rules: pathRules{Root: root, Defaults: builtinExcludes, DefaultDirs: presetExcludes(c.Preset), Excludes: c.Excludes, Includes: c.Includes}}
+	if c.Shard != "" {
+		// Validate has already checked the specification
+		opts.Shard, opts.Shards, _ = parseShard(c.Shard)
+	}
+	if len(c.Extensions) > 0 {
+		opts.Extensions = make(map[string]bool)
+		for _, ext := range c.Extensions {
+			opts.Extensions[strings.ToLower(ext)] = true
+		}
+	}
+	return opts
+}
+
+// Validate checks the whole configuration and reports every problem found,
+// each with a hint on how to fix it
+func (c Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Roots) == 0 {
+		addf("roots: no directory to scan; pass --root or list roots in %s", defaultConfigPath)
+	}
+	for _, root := range c.Roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			addf("roots: %q cannot be read (%v); check the path is relative to the working directory", root, err)
+		} else if !info.IsDir() {
+			addf("roots: %q is a file; roots must be directories", root)
+		}
+	}
+
+	if len(c.Patterns) == 0 {
+		addf("patterns: no pattern given; remove the empty list to use the default %s", defaultPattern)
+	}
+	for _, p := range c.Patterns {
+		re, err := compilePattern(p)
+		if err != nil {
+			addf("patterns: %q is not a valid regular expression: %v", p, err)
+		} else if _, _, err := patternGroups(re); err != nil {
+			addf("patterns: %v", err)
+		}
+	}
+
+	if re, err := compilePattern(c.TagPattern); c.TagPattern == "" {
+		addf("tag_pattern: must not be empty; remove it to use the default %s", defaultTagPattern)
+	} else if err != nil {
+		addf("tag_pattern: %q is not a valid regular expression: %v", c.TagPattern, err)
+	} else if re.NumSubexp() > 0 {
+		addf("tag_pattern: %q must not have capturing groups; use (?:...) instead", c.TagPattern)
+	}
+
+	for _, k := range c.Keywords {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, " \t[]") {
+			addf("keywords: %q must be a single word such as FIXME", k)
+		}
+	}
+
+	if c.Shard != "" {
+		if _, _, err := parseShard(c.Shard); err != nil {
+			addf("shard: %v", err)
+		}
+	}
+
+	for name, expr := range c.Views {
+		if _, err := parseFilter(expr); err != nil {
+			addf("views.%s: %v", name, err)
+		}
+	}
+
+	if c.Translation.Command != "" && c.Translation.To == "" {
+		addf("translation.to: must name the language of the report, e.g. en, when translation.command is set")
+	}
+
+	if _, err := suppressionFilter(c.Suppress); err != nil {
+		addf("%v", err)
+	}
+	if _, err := compileTicketRecognizers(c.Tickets); err != nil {
+		addf("%v", err)
+	}
+
+	for keyword, severity := range c.Severities {
+		if _, ok := severityRanks[severity]; !ok {
+			addf("severities.%s: %q must be info, warning or error", keyword, severity)
+		}
+	}
+	if _, ok := severityRanks[c.Policies.FailOnSeverity]; c.Policies.FailOnSeverity != "" && !ok {
+		addf("policies.fail_on_severity: %q must be info, warning or error", c.Policies.FailOnSeverity)
+	}
+
+	for _, tag := range c.ValidTags {
+		if strings.TrimSpace(tag) == "" {
+			addf("valid_tags: must not contain empty tags")
+			break
+		}
+	}
+	if c.Policies.StrictTags && len(c.ValidTags) == 0 {
+		addf("policies.strict_tags: needs valid_tags to list the allowed tags")
+	}
+
+	for alias, tag := range c.TagAliases {
+		if strings.TrimSpace(tag) == "" {
+			addf("tag_aliases.%s: must name the canonical tag", alias)
+		} else if next, ok := c.TagAliases[tag]; ok && next != tag {
+			addf("tag_aliases.%s: %q is itself an alias of %q; map %s to %q directly", alias, tag, next, alias, next)
+		}
+	}
+
+	for tag, owner := range c.Owners {
+		if strings.TrimSpace(owner.Team) == "" {
+			addf("owners.%s: team must name the owning team", tag)
+		}
+		if owner.Contact != "" {
+			if _, err := newNotifier(owner.Contact); err != nil {
+				addf("owners.%s.contact: %v", tag, err)
+			}
+		}
+		for _, step := range owner.Escalation {
+			if _, err := parseReminderRule(step); err != nil {
+				addf("owners.%s.escalation: %v", tag, err)
+			}
+		}
+	}
+
+	if c.Limits.MaxFileSize <= 0 {
+		addf("limits.max_file_size: must be a positive number of bytes, got %d", c.Limits.MaxFileSize)
+	}
+	if c.Limits.MaxTotalBytes < 0 {
+		addf("limits.max_total_bytes: must not be negative, got %d", c.Limits.MaxTotalBytes)
+	}
+	if c.Limits.MaxLineLength < 0 {
+		addf("limits.max_line_length: must not be negative, got %d", c.Limits.MaxLineLength)
+	}
+	if c.Limits.MaxItemsPerFile < 0 {
+		addf("limits.max_items_per_file: must not be negative, got %d", c.Limits.MaxItemsPerFile)
+	}
+	if c.Limits.MaxItemsPerDir < 0 {
+		addf("limits.max_items_per_dir: must not be negative, got %d", c.Limits.MaxItemsPerDir)
+	}
+	if c.Limits.MatchBudget != "" {
+		if d, err := time.ParseDuration(c.Limits.MatchBudget); err != nil || d <= 0 {
+			addf("limits.match_budget: %q must be a positive duration such as 10s", c.Limits.MatchBudget)
+		}
+	}
+	if c.Outputs.Tracker == "" {
+		addf("outputs.tracker: must name the tracker file, e.g. %s", defaultTrackerPath)
+	} else if err := checkCompression(c.Outputs.Tracker); err != nil {
+		addf("outputs.tracker: %q: %v", c.Outputs.Tracker, err)
+	}
+	if c.Outputs.BadgeYellow < 0 || c.Outputs.BadgeRed < c.Outputs.BadgeYellow {
+		addf("outputs.badge_yellow/badge_red: need 0 <= badge_yellow <= badge_red, got %d and %d", c.Outputs.BadgeYellow, c.Outputs.BadgeRed)
+	}
+	if c.Policies.StaleDays < 0 {
+		addf("policies.stale_days: must not be negative, got %d", c.Policies.StaleDays)
+	}
+
+	if c.Policies.GracePeriod != "" {
+		if _, err := parseDays(c.Policies.GracePeriod); err != nil {
+			addf("policies.grace_period: %v", err)
+		}
+	}
+	if c.Policies.NoNetIncrease && baseRef(c) == "" {
+		addf("policies.base: no_net_increase needs a base to compare with; pass --base with a tracker file or git revision such as origin/main")
+	}
+
+	if err := validateIssueLinks(c.Outputs.RepoURL, c.Outputs.IssueURLTemplate); err != nil {
+		addf("outputs.repo_url/issue_url_template: %v", err)
+	}
+	if c.Outputs.Forge != "" {
+		if _, err := newForge(c.Outputs.Forge); err != nil {
+			addf("outputs.forge: %v", err)
+		}
+	}
+	// The owners' contacts and escalations have been checked above
+	plain := c
+	plain.Owners = nil
+	if _, err := buildNotifiers(plain); err != nil {
+		addf("outputs.notify/routes: %v", err)
+	}
+	if _, err := buildEscalations(c); err != nil {
+		addf("policies.escalate: %v", err)
+	}
+	if _, err := buildReminderRules(plain); err != nil {
+		addf("policies.reminders: %v", err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// loadConfigFile overlays the JSON config file at path on c. Unknown keys are
+// rejected so that typos do not go unnoticed.
+func loadConfigFile(c *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// loadConfigEnv overlays the COLLECTTODO_* environment variables on c
+func loadConfigEnv(c *Config) error {
+	lists := map[string]*[]string{
+		"ROOTS":      &c.Roots,
+		"EXCLUDES":   &c.Excludes,
+		"INCLUDES":   &c.Includes,
+		"KEYWORDS":   &c.Keywords,
+		"VALID_TAGS": &c.ValidTags,
+	}
+	for name, field := range lists {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*field = splitList(v)
+		}
+	}
+	if v, ok := os.LookupEnv(envPrefix + "PATTERN"); ok {
+		c.Patterns = []string{v}
+	}
+	if v, ok := os.LookupEnv(envPrefix + "TAG_PATTERN"); ok {
+		c.TagPattern = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "MULTILINE"); ok {
+		c.Multiline = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "MATCH_BUDGET"); ok {
+		c.Limits.MatchBudget = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "IGNORE_CASE"); ok {
+		c.IgnoreCase = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "INCLUDE_GENERATED"); ok {
+		c.IncludeGenerated = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "INCLUDE_MD_TASKS"); ok {
+		c.IncludeMDTasks = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "RAW"); ok {
+		c.Raw = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "READ_ONLY"); ok {
+		c.ReadOnly = v == "true"
+	}
+	if v, ok := os.LookupEnv(envPrefix + "TRACKER"); ok {
+		c.Outputs.Tracker = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "MANIFEST"); ok {
+		c.Outputs.Manifest = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "FORGE"); ok {
+		c.Outputs.Forge = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "BADGE"); ok {
+		c.Outputs.Badge = v
+	}
+	ints := map[string]*int{
+		"MAX_FILE_SIZE":      &c.Limits.MaxFileSize,
+		"MAX_TOTAL_BYTES":    &c.Limits.MaxTotalBytes,
+		"MAX_LINE_LENGTH":    &c.Limits.MaxLineLength,
+		"MAX_ITEMS_PER_FILE": &c.Limits.MaxItemsPerFile,
+		"MAX_ITEMS_PER_DIR":  &c.Limits.MaxItemsPerDir,
+		"CONTEXT":            &c.Context,
+		"STALE_DAYS":         &c.Policies.StaleDays,
+		"BADGE_YELLOW":       &c.Outputs.BadgeYellow,
+		"BADGE_RED":          &c.Outputs.BadgeRed,
+	}
+	for name, field := range ints {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %q is not a number", envPrefix, name, v)
+			}
+			*field = n
+		}
+	}
+	return nil
+}
+
+// applyFlags overlays every flag set on the command line on c. Commands only
+// register the flags they support; unset flags leave c untouched.
+func applyFlags(c *Config, fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		list := func() []string {
+			if l, ok := f.Value.(*stringList); ok {
+				return *l
+			}
+			return splitList(f.Value.String())
+		}
+		switch f.Name {
+		case "root":
+			c.Roots = list()
+		case "blacklist":
+			c.Excludes = append(c.Excludes, list()...)
+		case "whitelist":
+			c.Includes = append(c.Includes, list()...)
+		case "pattern":
+			c.Patterns = list()
+		case "keywords":
+			c.Keywords = list()
+		case "tag-pattern":
+			c.TagPattern = f.Value.String()
+		case "multiline":
+			c.Multiline = f.Value.String() == "true"
+		case "context":
+			c.Context, _ = strconv.Atoi(f.Value.String())
+		case "max-items-per-file":
+			c.Limits.MaxItemsPerFile, _ = strconv.Atoi(f.Value.String())
+		case "max-items-per-dir":
+			c.Limits.MaxItemsPerDir, _ = strconv.Atoi(f.Value.String())
+		case "badge":
+			c.Outputs.Badge = f.Value.String()
+		case "badge-yellow":
+			c.Outputs.BadgeYellow, _ = strconv.Atoi(f.Value.String())
+		case "badge-red":
+			c.Outputs.BadgeRed, _ = strconv.Atoi(f.Value.String())
+		case "ignore-case":
+			c.IgnoreCase = f.Value.String() == "true"
+		case "include-generated":
+			c.IncludeGenerated = f.Value.String() == "true"
+		case "include-md-tasks":
+			c.IncludeMDTasks = f.Value.String() == "true"
+		case "raw":
+			c.Raw = f.Value.String() == "true"
+		case "read-only":
+			c.ReadOnly = f.Value.String() == "true"
+		case "shard":
+			c.Shard = f.Value.String()
+		case "preset":
+			c.Preset = f.Value.String()
+		case "tracker":
+			c.Outputs.Tracker = f.Value.String()
+		case "manifest":
+			c.Outputs.Manifest = f.Value.String()
+		case "forge":
+			c.Outputs.Forge = f.Value.String()
+		case "repo-url":
+			c.Outputs.RepoURL = f.Value.String()
+		case "issue-url-template":
+			c.Outputs.IssueURLTemplate = f.Value.String()
+		case "notify":
+			c.Outputs.Notify = append(c.Outputs.Notify, list()...)
+		case "route":
+			c.Outputs.Routes = append(c.Outputs.Routes, list()...)
+		case "escalate":
+			c.Policies.Escalate = append(c.Policies.Escalate, list()...)
+		case "remind":
+			c.Policies.Reminders = append(c.Policies.Reminders, list()...)
+		case "pin-permalinks":
+			c.Outputs.PinPermalinks = f.Value.String() == "true"
+		case "commit-status":
+			c.Outputs.CommitStatus = f.Value.String() == "true"
+		case "fail-on-overdue":
+			c.Policies.FailOnOverdue = f.Value.String() == "true"
+		case "fail-on-expired":
+			c.Policies.FailOnExpired = f.Value.String() == "true"
+		case "fail-on-severity":
+			c.Policies.FailOnSeverity = f.Value.String()
+		case "valid-tags":
+			c.ValidTags = list()
+		case "strict-tags":
+			c.Policies.StrictTags = f.Value.String() == "true"
+		case "no-net-increase":
+			c.Policies.NoNetIncrease = f.Value.String() == "true"
+		case "base":
+			c.Policies.Base = f.Value.String()
+		case "grace-period":
+			c.Policies.GracePeriod = f.Value.String()
+		case "stale-days":
+			c.Policies.StaleDays, _ = strconv.Atoi(f.Value.String())
+		}
+	})
+}
+
+// loadConfig assembles and validates the configuration for a command whose
+// flags have been parsed. configPath may be empty to use .collecttodo.json
+// when it exists. In GitHub Action mode the action inputs are applied
+// between the environment and the flags.
+func loadConfig(fs *flag.FlagSet, configPath string, githubAction bool) (Config, error) {
+	c := DefaultConfig()
+	var inputs map[string]string
+	if githubAction {
+		var err error
+		if inputs, err = actionInputs(); err != nil {
+			return c, err
+		}
+	}
+	if configPath == "" {
+		configPath = inputs["config"]
+	}
+	if configPath == "" {
+		configPath = os.Getenv(envPrefix + "CONFIG")
+	}
+	if configPath != "" {
+		if err := loadConfigFile(&c, configPath); err != nil {
+			return c, err
+		}
+	} else if _, err := os.Stat(defaultConfigPath); err == nil {
+		if err := loadConfigFile(&c, defaultConfigPath); err != nil {
+			return c, err
+		}
+	}
+	if err := loadConfigEnv(&c); err != nil {
+		return c, err
+	}
+	if err := applyActionInputs(&c, inputs); err != nil {
+		return c, err
+	}
+	applyFlags(&c, fs)
+	if err := applyPresets(&c); err != nil {
+		return c, err
+	}
+	return c, c.Validate()
+}
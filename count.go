@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// countTodos scans every root and counts the TODOs of each tag, without
+// reading or writing the tracker
+func countTodos(cfg Config) (map[string]int, error) {
+	scanner, err := NewScannerFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, root := range cfg.Roots {
+		result, err := scanner.Scan(cfg.ScanOptions(root))
+		if err != nil {
+			return nil, fmt.Errorf("scanning todos: %w", err)
+		}
+		for _, t := range result.Todos {
+			counts[t.Tag]++
+		}
+	}
+	return counts, nil
+}
+
+// formatCounts renders one tab-separated tag and count per line, sorted by
+// tag, followed by the total
+func formatCounts(counts map[string]int) string {
+	tags := make([]string, 0, len(counts))
+	total := 0
+	for tag, n := range counts {
+		tags = append(tags, tag)
+		total += n
+	}
+	sort.Strings(tags)
+	var b strings.Builder
+	for _, tag := range tags {
+		b.WriteString(fmt.Sprintf("%s\t%d\n", tag, counts[tag]))
+	}
+	b.WriteString(fmt.Sprintf("total\t%d\n", total))
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDays parses an age such as 30d, 2w, 6m or 1y into a number of days;
+// months count 30 days and years 365
+func parseDays(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	number, unit := value, 1
+	switch value[len(value)-1] {
+	case 'd':
+		number = value[:len(value)-1]
+	case 'w':
+		unit = 7
+		number = value[:len(value)-1]
+	case 'm':
+		unit = 30
+		number = value[:len(value)-1]
+	case 'y':
+		unit = 365
+		number = value[:len(value)-1]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age %q, expected a number of days such as 30d or 2w", value)
+	}
+	return n * unit, nil
+}
+
+// ageInDays returns how many days ago a TODO was first seen
+func ageInDays(t TodoItem, now time.Time) int {
+	seen, err := time.Parse("2006-01-02", t.Date)
+	if err != nil {
+		return 0
+	}
+	return int(now.Sub(seen).Hours() / 24)
+}
+
+// reminderRule notifies a target once about every item older than a given age
+type reminderRule struct {
+	name     string
+	days     int
+	notifier notifier
+	// tags, when set, restricts the rule to the items with one of them
+	tags map[string]bool
+}
+
+// parseReminderRule parses age=provider:target, e.g. 30d=slack:https://...
+func parseReminderRule(spec string) (reminderRule, error) {
+	var r reminderRule
+	age, target, ok := strings.Cut(spec, "=")
+	if !ok {
+		return r, fmt.Errorf("invalid reminder %q, expected age=provider:target", spec)
+	}
+	days, err := parseDays(age)
+	if err != nil {
+		return r, err
+	}
+	n, err := newNotifier(target)
+	if err != nil {
+		return r, err
+	}
+	// The rule name is recorded in the tracker, which is often committed, so it
+	// identifies the target by hash rather than by its (secret) webhook URL
+	kind, _, _ := strings.Cut(target, ":")
+	sum := sha1.Sum([]byte(target))
+	name := fmt.Sprintf("%s:%s:%s", age, kind, hex.EncodeToString(sum[:4]))
+	return reminderRule{name: name, days: days, notifier: n}, nil
+}
+
+// buildReminderRules creates the reminder rules listed in the configuration
+func buildReminderRules(cfg Config) ([]reminderRule, error) {
+	var rules []reminderRule
+	for _, spec := range cfg.Policies.Reminders {
+		r, err := parseReminderRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	// The owners' escalation steps only apply to their tag; the tag is part
+	// of the name so that a global rule to the same target still fires
+	for _, tag := range ownedTags(cfg) {
+		for _, spec := range cfg.Owners[tag].Escalation {
+			r, err := parseReminderRule(spec)
+			if err != nil {
+				return nil, fmt.Errorf("owner of %s: %v", tag, err)
+			}
+			r.name = tag + "@" + r.name
+			r.tags = map[string]bool{tag: true}
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// evaluateReminders sends every rule the items selected by only that reached
+// its age and the grace period and have not been reminded by it yet, and
+// records the reminder on the items. Exempted items are skipped. It reports
+// whether any item changed.
+func evaluateReminders(todos []TodoItem, rules []reminderRule, now time.Time, grace int, only filter) (bool, error) {
+	changed := false
+	for _, rule := range rules {
+		var due []int
+		for i, t := range todos {
+			if exempt(t, now.Format("2006-01-02")) || !only(t, now) || rule.tags != nil && !rule.tags[t.Tag] {
+				continue
+			}
+			if age := ageInDays(t, now); age >= rule.days && age >= grace && !containsString(t.Reminders, rule.name) {
+				due = append(due, i)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+		d := digest{Open: todos}
+		for _, i := range due {
+			d.Stale = append(d.Stale, todos[i])
+		}
+		if err := rule.notifier.Notify(d); err != nil {
+			return changed, err
+		}
+		for _, i := range due {
+			todos[i].Reminders = append(todos[i].Reminders, rule.name)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runDaemon periodically rescans the tree, sends change digests and
+// evaluates reminder rules
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	interval := fs.Duration("interval", 24*time.Hour, "Time between scans")
+	once := fs.Bool("once", false, "Run a single cycle and exit, for use from cron")
+	fs.Var(new(stringList), "notify", "Send a digest of changes to provider:target (slack, mattermost, teams, discord, email); repeatable")
+	fs.Var(new(stringList), "route", "Send the part of the digest with the given tags to a target: tag1,tag2=provider:target; repeatable")
+	fs.Var(new(stringList), "remind", "Remind about items older than an age: age=provider:target, e.g. 30d=slack:https://...; repeatable")
+	fs.String("grace-period", "", "Age such as 14d below which TODOs are never reminded about")
+	view := fs.String("view", "", "Only notify and remind about the TODOs of a view defined in the config file")
+	snapshots := fs.String("snapshots", "", "Directory where a dated snapshot of the TODOs is kept every day, for the history command")
+	keepDaily := fs.String("keep-daily", "", "Age such as 90d until which every daily snapshot is kept; older ones are thinned to one per week (default: keep all)")
+	keepWeekly := fs.String("keep-weekly", "", "Age such as 104w after which snapshots are deleted (default: keep weekly snapshots forever)")
+	fs.Parse(args)
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// The daemon remembers sent reminders and changes in the tracker
+	if err := refuseWrite(cfg, "the tracker the daemon keeps its state in"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	notifiers, _ := buildNotifiers(cfg)
+	rules, _ := buildReminderRules(cfg)
+	only, err := selectFilter(cfg, *view, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	retention, err := parseSnapshotRetention(*keepDaily, *keepWeekly)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for {
+		now := time.Now()
+		old, updated, _, err := scanAndTrack(cfg, now.Format("2006-01-02"))
+		if err != nil {
+			// Keep the daemon alive; the next cycle may succeed
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		} else {
+			d := buildDigest(old, updated, now.Format("2006-01-02"), staleDays(cfg)).filter(func(t TodoItem) bool { return only(t, now) })
+			if !d.Empty() {
+				for _, n := range notifiers {
+					if err := n.Notify(d); err != nil {
+						fmt.Fprintf(os.Stderr, "Error sending notification: %v\n", err)
+					}
+				}
+			}
+			changed, err := evaluateReminders(updated, rules, now, graceDays(cfg), only)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error sending reminder: %v\n", err)
+			}
+			if changed {
+				t, _ := loadTracker(cfg.Outputs.Tracker)
+				if err := saveTracker(cfg.Outputs.Tracker, TodoTracker{Todos: updated, Suppressed: t.Suppressed}); err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving tracker: %v\n", err)
+				}
+			}
+			if *snapshots != "" {
+				store := snapshotStore{dir: *snapshots}
+				today := now.Format("2006-01-02")
+				if err := store.Save("", today, scanResult{Received: now.UTC().Format(time.RFC3339), Todos: updated}); err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving snapshot: %v\n", err)
+				} else if err := store.Prune("", retention, now); err != nil {
+					fmt.Fprintf(os.Stderr, "Error pruning snapshots: %v\n", err)
+				}
+			}
+		}
+		if *once {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
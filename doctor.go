@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// doctorCheck is the outcome of one environment check
+type doctorCheck struct {
+	name   string
+	err    error
+	remedy string
+}
+
+func checkGit() doctorCheck {
+	c := doctorCheck{name: "git is available"}
+	if _, err := exec.LookPath("git"); err != nil {
+		c.err = err
+		c.remedy = "install git and make sure it is on PATH"
+		return c
+	}
+	if out, err := exec.Command("git", "rev-parse", "--is-inside-work-tree").CombinedOutput(); err != nil {
+		c.err = fmt.Errorf("not inside a git work tree: %s", strings.TrimSpace(string(out)))
+		c.remedy = "run from a checkout of the repository (actions/checkout in workflows)"
+	}
+	return c
+}
+
+// checkTokens verifies that every enabled integration has its credentials
+func checkTokens(cfg Config) []doctorCheck {
+	var checks []doctorCheck
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		c := doctorCheck{name: "GitHub token for PR comments"}
+		if os.Getenv("GITHUB_TOKEN") == "" && os.Getenv("GH_TOKEN") == "" {
+			c.err = fmt.Errorf("neither GITHUB_TOKEN nor GH_TOKEN is set")
+			c.remedy = "add `env: GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}` to the step and grant `pull-requests: write`"
+		}
+		checks = append(checks, c)
+	}
+	if strings.EqualFold(cfg.Outputs.Forge, "bitbucket") {
+		c := doctorCheck{name: "Bitbucket credentials"}
+		if os.Getenv("BITBUCKET_ACCESS_TOKEN") == "" && (os.Getenv("BITBUCKET_USERNAME") == "" || os.Getenv("BITBUCKET_APP_PASSWORD") == "") {
+			c.err = fmt.Errorf("no Bitbucket credentials in the environment")
+			c.remedy = "set BITBUCKET_ACCESS_TOKEN, or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD, as secured repository variables"
+		}
+		checks = append(checks, c)
+	}
+	usesEmail := false
+	for _, spec := range append(append(cfg.Outputs.Notify, cfg.Outputs.Routes...), cfg.Policies.Reminders...) {
+		if strings.Contains(spec, "email:") {
+			usesEmail = true
+		}
+	}
+	if usesEmail {
+		c := doctorCheck{name: "SMTP settings for e-mail notifications"}
+		if os.Getenv("SMTP_HOST") == "" || os.Getenv("SMTP_FROM") == "" {
+			c.err = fmt.Errorf("SMTP_HOST or SMTP_FROM is not set")
+			c.remedy = "set SMTP_HOST, SMTP_FROM and, if the server needs it, SMTP_USERNAME and SMTP_PASSWORD"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// checkTracker makes sure the tracker can be read and, unless readOnly,
+// written without touching its contents
+func checkTracker(path string, readOnly bool) []doctorCheck {
+	read := doctorCheck{name: "tracker " + path + " is readable"}
+	write := doctorCheck{name: "tracker " + path + " is writable"}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		// A missing tracker is created on the first run
+	case err != nil:
+		read.err = err
+		read.remedy = "check the file permissions"
+	default:
+		var tracker TodoTracker
+		if err := json.Unmarshal(data, &tracker); err != nil {
+			read.err = fmt.Errorf("not valid JSON: %v", err)
+			read.remedy = "fix or delete the file; a damaged tracker is silently replaced and first-seen dates are lost"
+		}
+	}
+
+	if readOnly {
+		// Probing would create a file; the tracker is never written anyway
+		return []doctorCheck{read}
+	}
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			write.err = err
+		} else {
+			f.Close()
+		}
+	} else {
+		f, err := os.CreateTemp(filepath.Dir(path), ".collecttodo-doctor-*")
+		if err != nil {
+			write.err = err
+		} else {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	if write.err != nil {
+		write.remedy = "make the file and its directory writable, or point --tracker somewhere writable"
+	}
+	return []doctorCheck{read, write}
+}
+
+// runDoctor checks the environment the tool runs in and explains how to fix
+// whatever is wrong
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	fs.String("forge", "", "Code hosting service to check credentials for (bitbucket)")
+	fs.Var(new(stringList), "notify", "Notification target to check; repeatable")
+	fs.Parse(args)
+
+	var checks []doctorCheck
+	cfg, err := opts.Config()
+	configCheck := doctorCheck{name: "configuration is valid", err: err}
+	if err != nil {
+		configCheck.remedy = "fix the settings above in " + defaultConfigPath + ", the COLLECTTODO_* variables or the flags"
+	}
+	checks = append(checks, configCheck, checkGit())
+	checks = append(checks, checkTokens(cfg)...)
+	checks = append(checks, checkTracker(cfg.Outputs.Tracker, cfg.ReadOnly)...)
+
+	failed := 0
+	for _, c := range checks {
+		if c.err == nil {
+			fmt.Printf("[ok]   %s\n", c.name)
+			continue
+		}
+		failed++
+		fmt.Printf("[fail] %s\n", c.name)
+		for _, line := range strings.Split(c.err.Error(), "\n") {
+			fmt.Printf("       %s\n", line)
+		}
+		fmt.Printf("       fix: %s\n", c.remedy)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, len(checks))
+		os.Exit(1)
+	}
+	fmt.Printf("\nAll %d checks passed\n", len(checks))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dueAttribute finds a due date written between the tag and the colon of a
+// TODO, e.g. TODO[infra][due:2025-03-01]: migrate DB
+var dueAttribute = regexp.MustCompile(`\[due:(\d{4}-\d{2}-\d{2})\]`)
+
+// parseDue returns the due date among the attributes of a TODO, or "" when
+// there is none or it is not a valid date
+func parseDue(attributes string) string {
+	m := dueAttribute.FindStringSubmatch(attributes)
+	if m == nil {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", m[1]); err != nil {
+		return ""
+	}
+	return m[1]
+}
+
+// overdue reports whether an item is past its due date on day now
+func overdue(t TodoItem, now string) bool {
+	return t.DueDate != "" && t.DueDate < now
+}
+
+// checkOverdue reports every item past its due date
+func checkOverdue(todos []TodoItem, now string) []policyViolation {
+	var violations []policyViolation
+	for _, t := range todos {
+		if overdue(t, now) {
+			violations = append(violations, policyViolation{
+				policy:  "fail-on-overdue",
+				message: fmt.Sprintf("%s %s (%s:%d) was due on %s", t.Label(), t.Description, t.File, t.Line, t.DueDate),
+			})
+		}
+	}
+	return violations
+}
+
+func formatOverdueMarkdown(todos []TodoItem, now string) string {
+	var b strings.Builder
+	for _, t := range todos {
+		if !overdue(t, now) {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("\n# Overdue\n\n")
+		}
+		b.WriteString(fmt.Sprintf("- **due %s** %s %s (%s:%d)\n", t.DueDate, t.Label(), t.Description, t.File, t.Line))
+	}
+	return b.String()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpConfig holds the settings used to deliver e-mail digests
+type smtpConfig struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// emailDelivery is one digest sent to one group of recipients
+type emailDelivery struct {
+	to     []string
+	digest digest
+}
+
+func (c smtpConfig) send(to []string, d digest) error {
+	body, err := formatEmailDigest(d)
+	if err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: TODO digest: %d new, %d stale, %d resolved\r\n", len(d.New), len(d.Stale), len(d.Resolved))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	var auth smtp.Auth
+	if c.username != "" {
+		auth = smtp.PlainAuth("", c.username, c.password, c.host)
+	}
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	return smtp.SendMail(addr, auth, c.from, to, msg.Bytes())
+}
+
+// emailNotifier sends digests to a fixed list of recipients, using the SMTP
+// settings from SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM
+type emailNotifier struct {
+	config smtpConfig
+	to     []string
+}
+
+func newEmailNotifier(recipients string) (*emailNotifier, error) {
+	n := &emailNotifier{
+		config: smtpConfig{
+			host:     os.Getenv("SMTP_HOST"),
+			port:     587,
+			username: os.Getenv("SMTP_USERNAME"),
+			password: os.Getenv("SMTP_PASSWORD"),
+			from:     os.Getenv("SMTP_FROM"),
+		},
+		to: splitList(recipients),
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT %q", port)
+		}
+		n.config.port = p
+	}
+	if n.config.host == "" || n.config.from == "" {
+		return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM must be set for e-mail notifications")
+	}
+	if len(n.to) == 0 {
+		return nil, fmt.Errorf("no e-mail recipients given")
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) Notify(d digest) error {
+	return n.config.send(n.to, d)
+}
+
+// splitByOwner routes each item of the digest to the e-mail owners of its
+// file. Items without an e-mail owner go to the fallback recipients.
+func splitByOwner(d digest, updated []TodoItem, owners codeowners, fallback []string) []emailDelivery {
+	byRecipient := make(map[string]*digest)
+	var recipients []string
+	unowned := &digest{}
+	route := func(t TodoItem, add func(*digest)) {
+		emails := owners.Emails(repoPath(t.File))
+		if len(emails) == 0 {
+			add(unowned)
+			return
+		}
+		for _, email := range emails {
+			rd, ok := byRecipient[email]
+			if !ok {
+				rd = &digest{}
+				byRecipient[email] = rd
+				recipients = append(recipients, email)
+			}
+			add(rd)
+		}
+	}
+	for _, t := range updated {
+		route(t, func(rd *digest) { rd.Open = append(rd.Open, t) })
+	}
+	for _, t := range d.New {
+		route(t, func(rd *digest) { rd.New = append(rd.New, t) })
+	}
+	for _, t := range d.Stale {
+		route(t, func(rd *digest) { rd.Stale = append(rd.Stale, t) })
+	}
+	for _, t := range d.Resolved {
+		route(t, func(rd *digest) { rd.Resolved = append(rd.Resolved, t) })
+	}
+
+	var deliveries []emailDelivery
+	for _, email := range recipients {
+		deliveries = append(deliveries, emailDelivery{to: []string{email}, digest: *byRecipient[email]})
+	}
+	if len(fallback) > 0 {
+		deliveries = append(deliveries, emailDelivery{to: fallback, digest: *unowned})
+	}
+	return deliveries
+}
+
+// runNotify implements the notify command
+func runNotify(args []string) {
+	if len(args) == 0 || args[0] != "email" {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo notify email [flags]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("notify email", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	host := fs.String("smtp-host", "", "SMTP server host")
+	port := fs.Int("smtp-port", 587, "SMTP server port")
+	username := fs.String("smtp-user", "", "SMTP username; the password is read from SMTP_PASSWORD")
+	from := fs.String("from", "", "Sender address")
+	to := fs.String("to", "", "Comma-separated list of recipients")
+	fs.Int("stale-days", 90, "Age in days after which an open TODO is listed as stale")
+	fs.String("grace-period", "", "Age such as 14d below which TODOs are never listed as stale")
+	view := fs.String("view", "", "Only send the TODOs of a view defined in the config file")
+	byOwner := fs.Bool("codeowners", false, "Send each CODEOWNERS e-mail owner the TODOs in their files; --to receives the rest")
+	fs.Parse(args[1:])
+
+	recipients := splitList(*to)
+	if *host == "" || *from == "" {
+		fmt.Fprintln(os.Stderr, "Error: --smtp-host and --from are required")
+		os.Exit(1)
+	}
+	if len(recipients) == 0 && !*byOwner {
+		fmt.Fprintln(os.Stderr, "Error: --to is required unless --codeowners is set")
+		os.Exit(1)
+	}
+	mailer := smtpConfig{
+		host:     *host,
+		port:     *port,
+		username: *username,
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     *from,
+	}
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	only, err := selectFilter(cfg, *view, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	now := time.Now().Format("2006-01-02")
+	old, updated, _, err := scanAndTrack(cfg, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+	updated = applyFilter(updated, only, time.Now())
+	d := buildDigest(applyFilter(old, only, time.Now()), updated, now, staleDays(cfg))
+
+	deliveries := []emailDelivery{{to: recipients, digest: d}}
+	if *byOwner {
+		owners, err := loadCodeowners()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading CODEOWNERS: %v\n", err)
+			os.Exit(1)
+		}
+		deliveries = splitByOwner(d, updated, owners, recipients)
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.digest.Empty() && len(delivery.digest.Stale) == 0 {
+			continue
+		}
+		if err := mailer.send(delivery.to, delivery.digest); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending e-mail to %s: %v\n", strings.Join(delivery.to, ", "), err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sent TODO digest to %s\n", strings.Join(delivery.to, ", "))
+	}
+}
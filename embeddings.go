@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EmbeddingsConfig names the command computing the embeddings of
+// descriptions for semantic search
+type EmbeddingsConfig struct {
+	// Command reads a JSON array of texts on standard input and prints a
+	// JSON array of as many vectors
+	Command string `json:"command,omitempty"`
+	// Cache is the file where the vectors of descriptions are kept between
+	// searches; nothing is kept when it is empty
+	Cache string `json:"cache,omitempty"`
+}
+
+// Embedder maps texts to vectors whose cosine similarity measures how close
+// their meanings are
+type Embedder interface {
+	Embed(texts []string) ([][]float64, error)
+}
+
+// commandEmbedder runs a shell command for every batch of texts
+type commandEmbedder struct {
+	command string
+	timeout time.Duration
+}
+
+func (c commandEmbedder) Embed(texts []string) ([][]float64, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("embeddings command: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var vectors [][]float64
+	if err := json.Unmarshal(out, &vectors); err != nil {
+		return nil, fmt.Errorf("embeddings command: output is not a JSON array of vectors: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embeddings command: got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// embeddingCache maps the SHA-256 of texts to their vectors
+type embeddingCache map[string][]float64
+
+func textKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadEmbeddingCache(path string) (embeddingCache, error) {
+	cache := make(embeddingCache)
+	if path == "" {
+		return cache, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// embed returns the vectors of texts, computing only those missing from the
+// cache, in one batch
+func (cache embeddingCache) embed(e Embedder, texts []string) ([][]float64, error) {
+	var missing []string
+	for _, text := range texts {
+		if _, ok := cache[textKey(text)]; !ok {
+			missing = append(missing, text)
+		}
+	}
+	if len(missing) > 0 {
+		vectors, err := e.Embed(missing)
+		if err != nil {
+			return nil, err
+		}
+		for i, text := range missing {
+			cache[textKey(text)] = vectors[i]
+		}
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = cache[textKey(text)]
+	}
+	return vectors, nil
+}
+
+// save writes the cache to path
+func (cache embeddingCache) save(path string) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when their
+// lengths differ or one is zero
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// semanticHit is an item found by the search command, with the cosine
+// similarity of a semantic search or the score of a word search
+type semanticHit struct {
+	TodoItem
+	Score float64 `json:"score"`
+}
+
+// semanticSearch ranks the items by how close their descriptions are to the
+// query, at most limit of them
+func semanticSearch(todos []TodoItem, query string, e Embedder, cache embeddingCache, limit int) ([]semanticHit, error) {
+	texts := []string{query}
+	for _, t := range todos {
+		texts = append(texts, t.Description)
+	}
+	vectors, err := cache.embed(e, texts)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(texts[1:], query) {
+		// Only descriptions are worth keeping
+		delete(cache, textKey(query))
+	}
+	hits := make([]semanticHit, len(todos))
+	for i, t := range todos {
+		hits[i] = semanticHit{t, cosine(vectors[0], vectors[i+1])}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
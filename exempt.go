@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// exemptPattern finds a collecttodo:exempt(reason, until=YYYY-MM-DD)
+// annotation in a TODO description
+var exemptPattern = regexp.MustCompile(`\s*collecttodo:exempt\(([^)]*)\)`)
+
+// Exemption excludes a TODO from policy enforcement, for an auditable reason
+// and optionally until a date
+type Exemption struct {
+	Reason string `json:"reason"`
+	Until  string `json:"until,omitempty"`
+}
+
+// parseExemption removes an exemption annotation from a description and
+// returns it, or nil when there is none
+func parseExemption(description string) (string, *Exemption) {
+	m := exemptPattern.FindStringSubmatchIndex(description)
+	if m == nil {
+		return description, nil
+	}
+	e := &Exemption{}
+	var reason []string
+	for _, arg := range strings.Split(description[m[2]:m[3]], ",") {
+		arg = strings.TrimSpace(arg)
+		if until, ok := strings.CutPrefix(arg, "until="); ok {
+			e.Until = strings.TrimSpace(until)
+		} else if arg != "" {
+			reason = append(reason, arg)
+		}
+	}
+	e.Reason = strings.Join(reason, ", ")
+	return strings.TrimSpace(description[:m[0]] + description[m[1]:]), e
+}
+
+// exempt reports whether a TODO is exempted from policies on the given day.
+// Exemptions with an unreadable expiry date are ignored.
+func exempt(t TodoItem, now string) bool {
+	if t.Exemption == nil {
+		return false
+	}
+	if t.Exemption.Until == "" {
+		return true
+	}
+	if _, err := time.Parse("2006-01-02", t.Exemption.Until); err != nil {
+		return false
+	}
+	return now <= t.Exemption.Until
+}
+
+// withoutExemptions returns the TODOs that policies apply to
+func withoutExemptions(todos []TodoItem, now string) []TodoItem {
+	var enforced []TodoItem
+	for _, t := range todos {
+		if !exempt(t, now) {
+			enforced = append(enforced, t)
+		}
+	}
+	return enforced
+}
+
+// formatExemptionsMarkdown lists the exempted TODOs with their reasons, so
+// that exemptions can be audited
+func formatExemptionsMarkdown(todos []TodoItem, now string) string {
+	var b strings.Builder
+	for _, t := range todos {
+		if t.Exemption == nil {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("\n# Exemptions\n\n")
+		}
+		reason := t.Exemption.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		status := "no expiry"
+		if t.Exemption.Until != "" {
+			status = "until " + t.Exemption.Until
+			if !exempt(t, now) {
+				status += ", expired"
+			}
+		}
+		b.WriteString(fmt.Sprintf("- %s %s (%s:%d): %s (%s)\n", t.Label(), t.Description, t.File, t.Line, reason, status))
+	}
+	return b.String()
+}
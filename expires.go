@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// expiresToken finds an expiry date in the attributes or the description of
+// a TODO, e.g. TODO[hack][expires:2025-06-01]: or "drop the shim
+// expires:2025-06-01"
+var expiresToken = regexp.MustCompile(`\s*\bexpires:(\d{4}-\d{2}-\d{2})\b`)
+
+// parseExpires returns text without its expiry token, and the expiry date.
+// A token that is not a valid date is left in place.
+func parseExpires(text string) (string, string) {
+	loc := expiresToken.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return text, ""
+	}
+	date := text[loc[2]:loc[3]]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return text, ""
+	}
+	return strings.TrimSpace(text[:loc[0]] + text[loc[1]:]), date
+}
+
+// expired reports whether an item's expiry date has passed on day now
+func expired(t TodoItem, now string) bool {
+	return t.Expires != "" && t.Expires < now
+}
+
+// checkExpired reports every item past its expiry date
+func checkExpired(todos []TodoItem, now string) []policyViolation {
+	var violations []policyViolation
+	for _, t := range todos {
+		if expired(t, now) {
+			violations = append(violations, policyViolation{
+				policy:  "fail-on-expired",
+				message: fmt.Sprintf("%s %s (%s:%d) expired on %s", t.Label(), t.Description, t.File, t.Line, t.Expires),
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// forge publishes scan results to a code hosting service
+type forge interface {
+	// Permalink returns a URL pointing at the given line of a scanned file
+	Permalink(file string, line int) string
+	// PermalinkAt returns a URL pointing at a line of a file at a commit
+	PermalinkAt(commit, file string, line int) string
+	// PostSummary posts the markdown summary on the current pull request
+	PostSummary(markdown string) error
+	// PostAnnotations attaches one annotation per TODO to the current commit
+	PostAnnotations(todos []TodoItem) error
+}
+
+func newForge(name string) (forge, error) {
+	switch strings.ToLower(name) {
+	case "bitbucket":
+		return newBitbucketForge()
+	default:
+		return nil, fmt.Errorf("unknown forge %q", name)
+	}
+}
+
+// sendJSON sends body as JSON and fails on any non-2xx response
+func sendJSON(client *http.Client, method, url string, body interface{}, setAuth func(*http.Request)) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setAuth != nil {
+		setAuth(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// truncate shortens s to at most limit bytes, ending with "...", without
+// cutting a multibyte character in two
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	end := limit - 3
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
+}
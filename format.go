@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Report is everything a run has to say, for the output formats to render
+type Report struct {
+	// Todos are the open TODOs selected for the report
+	Todos []TodoItem
+	// New and Resolved are the TODOs first seen and gone since the last run
+	New      []TodoItem
+	Resolved []TodoItem
+	// Warnings are the problems met by the scan, for the appendix
+	Warnings   []ScanWarning
+	Violations []policyViolation
+	// Now is the day of the run, YYYY-MM-DD, and Generated its time,
+	// RFC 3339
+	Now       string
+	Generated string
+	// Roots are the scanned directories
+	Roots []string
+	// Link returns the permalink of a line, or is nil
+	Link func(file string, line int) string
+	// Base is the revision the change is compared with, if known
+	Base string
+	// Owners is the tag ownership registry
+	Owners map[string]TagOwner
+	// IssueLink returns the URL of an issue reference, or is nil
+	IssueLink func(ref string) string
+	// GroupBy is how the markdown summary is sectioned: tag or assignee
+	GroupBy string
+	// Cluster is the similarity from which items are grouped, or 0
+	Cluster float64
+	// CountOrphans notes the number of items without a ticket
+	CountOrphans bool
+	// Executive is the executive summary written by the summarizer, or ""
+	Executive string
+	// Suppressed is the number of items left out by suppression rules,
+	// when they are counted
+	Suppressed int
+	// StaleDays is the age after which open items are stale, or 0
+	StaleDays int
+}
+
+// markdownOptions controls how the markdown summary is rendered
+type markdownOptions struct {
+	// Link returns the permalink of a line, or is nil
+	Link func(file string, line int) string
+	// Owners is the tag ownership registry
+	Owners map[string]TagOwner
+	// IssueLink returns the URL of an issue reference such as #123, or is
+	// nil
+	IssueLink func(ref string) string
+	// Now is the current day, YYYY-MM-DD, for marking expired items
+	Now string
+	// Cluster, when above 0, is the similarity from which items of a
+	// section are grouped into one entry
+	Cluster float64
+}
+
+// outputFormat renders a report for --format
+type outputFormat func(r Report) (string, error)
+
+// outputFormats lists the values accepted by --format
+var outputFormats = map[string]outputFormat{
+	"markdown":       formatMarkdownReport,
+	"atom":           formatAtomFeed,
+	"checkstyle":     formatCheckstyleReport,
+	"codeclimate":    formatCodeClimateReport,
+	"json":           formatJSONReport,
+	"csv":            formatCSVReport,
+	"gh-annotations": formatGitHubAnnotations,
+	"html":           formatHTMLReport,
+	"html-email":     formatHTMLEmailReport,
+	"rdf-github":     formatReviewComments,
+	"sarif":          formatSARIFReport,
+	"sonar":          formatSonarReport,
+}
+
+// formatNames returns the supported formats, for help and error messages
+func formatNames() string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// lookupFormat returns the renderer of a --format value
+func lookupFormat(name string) (outputFormat, error) {
+	f, ok := outputFormats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (supported: %s)", name, formatNames())
+	}
+	return f, nil
+}
+
+// groupings lists the values accepted by --group-by
+var groupings = map[string]bool{"tag": true, "assignee": true}
+
+// formatMarkdownReport renders the summary posted to pull requests
+func formatMarkdownReport(r Report) (string, error) {
+	return ComposeReport(r, DefaultSections()...), nil
+}
+
+// jsonReport is the output of --format=json
+type jsonReport struct {
+	Generated  string          `json:"generated"`
+	Roots      []string        `json:"roots"`
+	Todos      []TodoItem      `json:"todos"`
+	New        []TodoItem      `json:"new"`
+	Resolved   []TodoItem      `json:"resolved"`
+	Warnings   []ScanWarning   `json:"warnings"`
+	Violations []jsonViolation `json:"violations"`
+	Suppressed int             `json:"suppressed,omitempty"`
+	Executive  string          `json:"executive_summary,omitempty"`
+}
+
+type jsonViolation struct {
+	Policy  string `json:"policy"`
+	Message string `json:"message"`
+}
+
+// formatJSONReport renders the report for other tools to consume; lists are
+// never null
+func formatJSONReport(r Report) (string, error) {
+	nonNil := func(todos []TodoItem) []TodoItem {
+		if todos == nil {
+			return []TodoItem{}
+		}
+		return todos
+	}
+	out := jsonReport{
+		Generated:  r.Generated,
+		Roots:      r.Roots,
+		Todos:      nonNil(r.Todos),
+		New:        nonNil(r.New),
+		Resolved:   nonNil(r.Resolved),
+		Warnings:   r.Warnings,
+		Violations: []jsonViolation{},
+		Suppressed: r.Suppressed,
+		Executive:  r.Executive,
+	}
+	if out.Roots == nil {
+		out.Roots = []string{}
+	}
+	if out.Warnings == nil {
+		out.Warnings = []ScanWarning{}
+	}
+	for _, v := range r.Violations {
+		out.Violations = append(out.Violations, jsonViolation{v.policy, v.message})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// csvColumns are the columns of --format=csv
+var csvColumns = []string{"id", "keyword", "tag", "severity", "priority", "description", "file", "line", "column", "date", "due_date", "expires", "author", "assignees", "ticket", "references"}
+
+// formatCSVReport renders one row per TODO for spreadsheets; lists are
+// separated by semicolons
+func formatCSVReport(r Report) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(csvColumns)
+	for _, t := range r.Todos {
+		keyword := t.Keyword
+		if keyword == "" {
+			keyword = defaultKeyword
+		}
+		w.Write([]string{
+			t.ID, keyword, t.Tag, t.Severity, t.Priority, csvCell(t.Description),
+			repoPath(t.File), fmt.Sprint(t.Line), fmt.Sprint(t.RuneColumn), t.Date, t.DueDate, t.Expires,
+			t.Author, strings.Join(t.Assignees, ";"), t.Ticket, strings.Join(t.References, ";"),
+		})
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// csvCell keeps spreadsheets from evaluating text as a formula
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hookTimeout bounds every hook command
+const hookTimeout = time.Minute
+
+// HooksConfig names shell commands run around every scan; each reads the
+// JSON payload of its event on standard input
+type HooksConfig struct {
+	// PreScan runs before the scan; a failure stops the run
+	PreScan string `json:"pre_scan,omitempty"`
+	// PostScan runs once the tracker is updated
+	PostScan string `json:"post_scan,omitempty"`
+	// OnNewTodo runs once for every TODO first seen by the scan
+	OnNewTodo string `json:"on_new_todo,omitempty"`
+}
+
+// hookEvent is the payload of a hook; fields not relevant to the event are
+// left out
+type hookEvent struct {
+	Event    string        `json:"event"`
+	Roots    []string      `json:"roots"`
+	Tracker  string        `json:"tracker"`
+	Total    *int          `json:"total,omitempty"`
+	New      []TodoItem    `json:"new,omitempty"`
+	Resolved []TodoItem    `json:"resolved,omitempty"`
+	Warnings []ScanWarning `json:"warnings,omitempty"`
+	Todo     *TodoItem     `json:"todo,omitempty"`
+}
+
+// runHook runs a hook command with the event on standard input; its output
+// goes to standard error, so it never mixes with the report
+func runHook(command string, event hookEvent) error {
+	if command == "" {
+		return nil
+	}
+	input, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = strings.NewReader(string(input))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook: %w", event.Event, err)
+	}
+	return nil
+}
+
+// runPostScanHooks runs the post_scan hook, then on_new_todo for every new
+// item. The tracker is already saved, so failures are only reported.
+func runPostScanHooks(cfg Config, d digest, warnings []ScanWarning) {
+	base := hookEvent{Roots: cfg.Roots, Tracker: cfg.Outputs.Tracker}
+	if cfg.Hooks.PostScan != "" {
+		event := base
+		total := d.Total()
+		event.Event, event.Total, event.New, event.Resolved, event.Warnings = "post_scan", &total, d.New, d.Resolved, warnings
+		if err := runHook(cfg.Hooks.PostScan, event); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if cfg.Hooks.OnNewTodo == "" {
+		return
+	}
+	for i := range d.New {
+		event := base
+		event.Event, event.Todo = "on_new_todo", &d.New[i]
+		if err := runHook(cfg.Hooks.OnNewTodo, event); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s:%d: %v\n", d.New[i].File, d.New[i].Line, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+)
+
+// emailTemplates render HTML that mail clients show as intended: the layout
+// is made of tables and every element carries its own inline style, since
+// Outlook ignores most CSS layout and Gmail drops <style> blocks. They are
+// meant for e-mail; the dashboard of the aggregation server has its own.
+var emailTemplates = template.Must(template.New("email").Parse(`
+{{define "open"}}<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=UTF-8" />
+<meta name="viewport" content="width=device-width, initial-scale=1.0" />
+<title>{{.}}</title>
+</head>
+<body style="margin:0;padding:0;background-color:#f6f8fa;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0" style="background-color:#f6f8fa;">
+<tr><td align="center" style="padding:24px 12px;">
+<table role="presentation" width="640" cellpadding="0" cellspacing="0" border="0" style="width:640px;max-width:100%;background-color:#ffffff;border:1px solid #d0d7de;">
+<tr><td style="padding:20px 24px;font-family:Arial,Helvetica,sans-serif;font-size:14px;line-height:20px;color:#24292f;">
+<h1 style="margin:0 0 8px 0;font-family:Arial,Helvetica,sans-serif;font-size:22px;line-height:28px;color:#24292f;">{{.}}</h1>
+{{end}}
+
+{{define "close"}}</td></tr>
+</table>
+</td></tr>
+</table>
+</body>
+</html>
+{{end}}
+
+{{define "heading"}}<h2 style="margin:20px 0 8px 0;font-family:Arial,Helvetica,sans-serif;font-size:17px;line-height:22px;color:#24292f;">{{.}}</h2>
+{{end}}
+
+{{define "items"}}<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0" style="border-collapse:collapse;">
+{{range .}}<tr>
+<td valign="top" style="padding:6px 8px 6px 0;border-bottom:1px solid #d0d7de;font-family:Arial,Helvetica,sans-serif;font-size:13px;font-weight:bold;color:#24292f;white-space:nowrap;">{{.Label}}</td>
+<td valign="top" style="padding:6px 8px;border-bottom:1px solid #d0d7de;font-family:Arial,Helvetica,sans-serif;font-size:14px;color:#24292f;">{{if .Priority}}<span style="color:#cf222e;font-weight:bold;">{{.Priority}}</span> {{end}}{{.Description}}{{if .Due}} <span style="color:#9a6700;">(due {{.Due}})</span>{{end}}<br />
+<span style="font-family:Consolas,Menlo,'Courier New',monospace;font-size:12px;color:#57606a;">{{if .URL}}<a href="{{.URL}}" style="color:#0969da;text-decoration:underline;">{{.Location}}</a>{{else}}{{.Location}}{{end}}</span></td>
+<td valign="top" align="right" style="padding:6px 0 6px 8px;border-bottom:1px solid #d0d7de;font-family:Arial,Helvetica,sans-serif;font-size:12px;color:#57606a;white-space:nowrap;">{{.Date}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+
+{{define "digest"}}{{template "open" "TODO Digest"}}<p style="margin:0 0 12px 0;color:#57606a;">{{.Total}} open, {{len .New}} new, {{len .Stale}} stale, {{len .Resolved}} resolved</p>
+{{if .New}}{{template "heading" "New"}}{{template "items" .New}}{{end}}
+{{if .Stale}}{{template "heading" "Stale"}}{{template "items" .Stale}}{{end}}
+{{if .Resolved}}{{template "heading" "Resolved"}}{{template "items" .Resolved}}{{end}}
+{{template "close"}}{{end}}
+
+{{define "report"}}{{template "open" "TODO Summary"}}<p style="margin:0 0 12px 0;color:#57606a;">{{.Total}} open, {{.New}} new, {{.Resolved}} resolved</p>
+{{if .Executive}}<p style="margin:0 0 12px 0;">{{.Executive}}</p>
+{{end}}{{range .Sections}}{{template "heading" .Title}}{{if .Owner}}<p style="margin:0 0 8px 0;color:#57606a;font-style:italic;">Owner: {{.Owner}}</p>
+{{end}}{{template "items" .Items}}{{end}}
+{{if .Violations}}{{template "heading" "Policy Violations"}}<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0">
+{{range .Violations}}<tr><td style="padding:4px 0;font-family:Arial,Helvetica,sans-serif;font-size:14px;color:#cf222e;"><b>{{.Policy}}</b>: {{.Message}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Warnings}}{{template "heading" "Scan Warnings"}}<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0">
+{{range .Warnings}}<tr><td style="padding:4px 0;font-family:Arial,Helvetica,sans-serif;font-size:13px;color:#57606a;"><b>{{.Kind}}</b>: {{.Message}}</td></tr>
+{{end}}</table>
+{{end}}{{template "close"}}{{end}}
+`))
+
+// emailItem is an item as shown in an e-mail
+type emailItem struct {
+	Label, Description, Priority, Due, Date string
+	Location, URL                           string
+}
+
+func emailItems(todos []TodoItem, link func(string, int) string) []emailItem {
+	items := make([]emailItem, len(todos))
+	for i, t := range todos {
+		items[i] = emailItem{
+			Label:       t.Label(),
+			Description: t.Description,
+			Priority:    t.Priority,
+			Due:         t.DueDate,
+			Date:        t.Date,
+			Location:    fmt.Sprintf("%s:%d", repoPath(t.File), t.Line),
+		}
+		if t.Permalink != "" {
+			items[i].URL = t.Permalink
+		} else if link != nil {
+			items[i].URL = link(t.File, t.Line)
+		}
+	}
+	return items
+}
+
+// formatEmailDigest renders a digest as e-mail-safe HTML
+func formatEmailDigest(d digest) (string, error) {
+	data := struct {
+		Total                int
+		New, Stale, Resolved []emailItem
+	}{d.Total(), emailItems(d.New, nil), emailItems(d.Stale, nil), emailItems(d.Resolved, nil)}
+	var b bytes.Buffer
+	err := emailTemplates.ExecuteTemplate(&b, "digest", data)
+	return b.String(), err
+}
+
+// formatHTMLEmailReport renders the summary as e-mail-safe HTML, one section
+// per tag, for --format=html-email
+func formatHTMLEmailReport(r Report) (string, error) {
+	type section struct {
+		Title, Owner string
+		Items        []emailItem
+	}
+	type violation struct{ Policy, Message string }
+	byTag := make(map[string][]TodoItem)
+	for _, t := range r.Todos {
+		byTag[t.Tag] = append(byTag[t.Tag], t)
+	}
+	tags := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var sections []section
+	for _, tag := range tags {
+		items := byTag[tag]
+		sortByUrgency(items)
+		title := tag
+		if title == "" {
+			title = "Untagged"
+		}
+		sections = append(sections, section{title, r.Owners[tag].Team, emailItems(items, r.Link)})
+	}
+	var violations []violation
+	for _, v := range r.Violations {
+		violations = append(violations, violation{v.policy, v.message})
+	}
+	data := struct {
+		Total, New, Resolved int
+		Executive            string
+		Sections             []section
+		Violations           []violation
+		Warnings             []ScanWarning
+	}{len(r.Todos), len(r.New), len(r.Resolved), r.Executive, sections, violations, r.Warnings}
+	var b bytes.Buffer
+	err := emailTemplates.ExecuteTemplate(&b, "report", data)
+	return b.String(), err
+}
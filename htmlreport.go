@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+)
+
+// htmlReportTemplate is a single self-contained page, with its styles and
+// script inline, that can be published as a CI artifact and opened offline
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>TODO Report</title>
+<style>
+body { font-family: system-ui, -apple-system, "Segoe UI", sans-serif; margin: 2em; color: #24292f; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #57606a; margin-bottom: 1.2em; }
+form { display: flex; gap: 1em; flex-wrap: wrap; margin-bottom: 1em; }
+label { font-size: 0.9em; color: #57606a; }
+input, select { font: inherit; padding: 0.2em 0.4em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4em 0.6em; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+th { background: #f6f8fa; white-space: nowrap; }
+th[data-key] { cursor: pointer; user-select: none; }
+th[data-dir="asc"]::after { content: " \25B2"; }
+th[data-dir="desc"]::after { content: " \25BC"; }
+td.num { text-align: right; }
+code { font-size: 0.9em; }
+.badge { font-weight: bold; color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>TODO Report</h1>
+<p class="meta">{{len .Items}} TODOs{{if .Roots}} in {{range $i, $r := .Roots}}{{if $i}}, {{end}}<code>{{$r}}</code>{{end}}{{end}}, generated {{.Generated}}. <span id="shown"></span></p>
+<form onsubmit="return false">
+<label>Tag <select id="tag"><option value="">all</option>{{range .Tags}}<option>{{.}}</option>{{end}}</select></label>
+<label>File <input id="file" type="search" placeholder="path contains"></label>
+<label>Older than <input id="age" type="number" min="0" style="width:5em"> days</label>
+<label>Text <input id="text" type="search" placeholder="description contains"></label>
+</form>
+<table>
+<thead><tr><th data-key="tag">Tag</th><th data-key="description">Description</th><th data-key="file">File</th><th data-key="line" data-num>Line</th><th data-key="age" data-num>Age (days)</th><th data-key="date">First seen</th></tr></thead>
+<tbody>
+{{range .Items}}<tr data-tag="{{.Tag}}" data-description="{{.Description}}" data-file="{{.File}}" data-line="{{.Line}}" data-age="{{.Age}}" data-date="{{.Date}}">
+<td><code>{{.Label}}</code></td>
+<td>{{if .Priority}}<span class="badge">{{.Priority}}</span> {{end}}{{.Description}}</td>
+<td>{{if .URL}}<a href="{{.URL}}"><code>{{.File}}</code></a>{{else}}<code>{{.File}}</code>{{end}}</td>
+<td class="num">{{.Line}}</td>
+<td class="num">{{.Age}}</td>
+<td>{{.Date}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{if .Warnings}}<h2>Scan warnings</h2>
+<p class="meta">These files were skipped or not read completely; their TODOs may be missing above.</p>
+<table>
+<thead><tr><th>Kind</th><th>Warning</th></tr></thead>
+<tbody class="warnings">
+{{range .Warnings}}<tr><td><code>{{.Kind}}</code></td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}<script>
+(function () {
+  var body = document.querySelector("tbody");
+  var headers = document.querySelectorAll("th[data-key]");
+  var rows = Array.prototype.slice.call(body.rows);
+  var inputs = ["tag", "file", "age", "text"].map(function (id) { return document.getElementById(id); });
+  function filter() {
+    var tag = inputs[0].value, file = inputs[1].value.toLowerCase();
+    var age = parseInt(inputs[2].value, 10), text = inputs[3].value.toLowerCase();
+    var shown = 0;
+    rows.forEach(function (r) {
+      var d = r.dataset;
+      var ok = (!tag || d.tag === tag) &&
+        (!file || d.file.toLowerCase().indexOf(file) >= 0) &&
+        (isNaN(age) || parseInt(d.age, 10) > age) &&
+        (!text || d.description.toLowerCase().indexOf(text) >= 0);
+      r.style.display = ok ? "" : "none";
+      if (ok) shown++;
+    });
+    document.getElementById("shown").textContent = shown + " shown.";
+  }
+  inputs.forEach(function (i) { i.addEventListener("input", filter); });
+  headers.forEach(function (th) {
+    th.addEventListener("click", function () {
+      var key = th.dataset.key, num = th.hasAttribute("data-num");
+      var dir = th.dataset.dir === "asc" ? "desc" : "asc";
+      headers.forEach(function (o) { delete o.dataset.dir; });
+      th.dataset.dir = dir;
+      rows.sort(function (a, b) {
+        var x = a.dataset[key], y = b.dataset[key];
+        var c = num ? x - y : x.localeCompare(y);
+        return dir === "asc" ? c : -c;
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+  filter();
+})();
+</script>
+</body>
+</html>
+`))
+
+// htmlReportItem is a row of the HTML report
+type htmlReportItem struct {
+	Label, Tag, Description, Priority, File, Date, URL string
+	Line, Age                                          int
+}
+
+// formatHTMLReport renders the TODOs as a standalone HTML page with
+// filtering and sortable columns, for --format=html
+func formatHTMLReport(r Report) (string, error) {
+	now, err := time.Parse("2006-01-02", r.Now)
+	if err != nil {
+		now = time.Now()
+	}
+	items := append([]TodoItem(nil), r.Todos...)
+	sortByUrgency(items)
+	rows := make([]htmlReportItem, len(items))
+	tags := make(map[string]bool)
+	for i, t := range items {
+		rows[i] = htmlReportItem{
+			Label:       t.Label(),
+			Tag:         t.Tag,
+			Description: t.Description,
+			Priority:    t.Priority,
+			File:        repoPath(t.File),
+			Date:        t.Date,
+			Line:        t.Line,
+			Age:         ageInDays(t, now),
+		}
+		if t.Permalink != "" {
+			rows[i].URL = t.Permalink
+		} else if r.Link != nil {
+			rows[i].URL = r.Link(t.File, t.Line)
+		}
+		tags[t.Tag] = true
+	}
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	err = htmlReportTemplate.Execute(&b, struct {
+		Items     []htmlReportItem
+		Tags      []string
+		Roots     []string
+		Generated string
+		Warnings  []ScanWarning
+	}{rows, names, r.Roots, r.Generated, r.Warnings})
+	if err != nil {
+		return "", fmt.Errorf("rendering HTML: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const workflowPath = ".github/workflows/todo-summary.yml"
+
+const starterWorkflow = `name: TODO-Summary
+permissions:
+  contents: read
+  pull-requests: write # Required to update PR with TODO summary
+
+on:
+  pull_request:
+    types: [opened, synchronize, reopened]
+
+jobs:
+  todo-summary:
+    name: TODO Summary
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+
+      - name: Run CollectTODO
+        uses: kao-fu/CollectTODO@main
+        env:
+          GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+        with:
+          config: .collecttodo.json
+`
+
+// Extensions of files that never contain TODO comments
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true,
+	".pdf": true, ".zip": true, ".gz": true, ".jar": true, ".woff": true, ".woff2": true,
+}
+
+// detectExcludes looks at the files in the tree and at .gitignore and returns
+// the excludes a new configuration should start with
+func detectExcludes(root string) []string {
+	seen := map[string]bool{".git": true}
+	markers := make(map[string]bool)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" || d.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		markers[d.Name()] = true
+		ext := strings.ToLower(filepath.Ext(path))
+		markers[ext] = true
+		if binaryExtensions[ext] {
+			seen[ext] = true
+		}
+		return nil
+	})
+	for _, p := range presets {
+		for _, m := range p.detect {
+			if markers[m] {
+				for _, e := range p.excludes {
+					seen[e] = true
+				}
+				break
+			}
+		}
+	}
+
+	// Plain names from .gitignore; globs and negations need real gitignore
+	// matching, which the ignore list does not do
+	if f, err := os.Open(filepath.Join(root, ".gitignore")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.Trim(strings.TrimSpace(scanner.Text()), "/")
+			if line == "" || strings.HasPrefix(line, "#") || strings.ContainsAny(line, "*?[!") {
+				continue
+			}
+			seen[line] = true
+		}
+	}
+
+	excludes := make([]string, 0, len(seen))
+	for e := range seen {
+		excludes = append(excludes, e)
+	}
+	sort.Strings(excludes)
+	return excludes
+}
+
+// confirm asks a yes/no question, returning def when the answer is empty
+func confirm(in *bufio.Reader, question string, def bool) bool {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	fmt.Printf("%s %s ", question, hint)
+	answer, _ := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" {
+		return def
+	}
+	return answer == "y" || answer == "yes"
+}
+
+// writeNewFile writes a file unless it exists and overwriting is not allowed
+func writeNewFile(path string, data []byte, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// runInit scaffolds a configuration file, and optionally a workflow, for the
+// repository in the working directory
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Do not ask questions; accept the detected settings")
+	workflow := fs.Bool("workflow", false, "Also write "+workflowPath)
+	force := fs.Bool("force", false, "Overwrite existing files")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: collecttodo init [-yes] [-workflow] [-force]\n\n"+
+			"Writes a starter %s. The configuration is JSON, not YAML: the tool\n"+
+			"only uses the standard library, which has no YAML parser.\n\n", defaultConfigPath)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := refuseWrite(envConfig(), defaultConfigPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Excludes = detectExcludes(".")
+	cfg.Includes = []string{}
+	cfg.Outputs.Notify = []string{}
+	cfg.Outputs.Routes = []string{}
+	cfg.Policies.Escalate = []string{}
+	cfg.Policies.Reminders = []string{}
+
+	interactive := false
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		interactive = !*yes
+	}
+	if interactive {
+		in := bufio.NewReader(os.Stdin)
+		fmt.Printf("Detected excludes: %s\n", strings.Join(cfg.Excludes, ", "))
+		if !confirm(in, "Use these excludes?", true) {
+			cfg.Excludes = []string{}
+		}
+		if !*workflow {
+			*workflow = confirm(in, "Add a GitHub workflow that comments the summary on pull requests?", false)
+		}
+	}
+
+	data, _ := json.MarshalIndent(cfg, "", "  ")
+	if err := writeNewFile(defaultConfigPath, append(data, '\n'), *force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", defaultConfigPath)
+
+	if *workflow {
+		if err := writeNewFile(workflowPath, []byte(starterWorkflow), *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s\n", workflowPath)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TranslationConfig turns on language detection and names the command that
+// translates descriptions
+type TranslationConfig struct {
+	// To is the language of the report, e.g. en; detection is off when it
+	// is empty
+	To string `json:"to,omitempty"`
+	// Command reads a description on standard input and prints its
+	// translation. COLLECTTODO_FROM and COLLECTTODO_TO hold the languages.
+	Command string `json:"command,omitempty"`
+}
+
+// Translator translates a text from one language into another
+type Translator interface {
+	Translate(text, from, to string) (string, error)
+}
+
+// commandTranslator runs a shell command for every translation
+type commandTranslator struct {
+	command string
+	timeout time.Duration
+}
+
+func (c commandTranslator) Translate(text, from, to string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Env = append(os.Environ(), envPrefix+"FROM="+from, envPrefix+"TO="+to)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("translation command: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// scriptLanguages are the languages recognized by their writing system, in
+// the order they are tried: kana come before Han, which Japanese shares
+// with Chinese
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// stopWords are frequent short words of languages written in Latin script
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "to", "of", "this", "for", "with", "it", "not", "when", "should"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "wenn", "noch", "auch", "sollte"},
+	"fr": {"le", "la", "les", "et", "est", "pas", "pour", "avec", "une", "des", "quand", "il"},
+	"es": {"el", "los", "las", "y", "es", "no", "para", "con", "una", "que", "cuando", "del"},
+	"pt": {"o", "os", "as", "e", "não", "para", "com", "uma", "que", "quando", "do", "da"},
+	"it": {"il", "lo", "gli", "e", "è", "non", "per", "con", "una", "che", "quando", "della"},
+	"nl": {"de", "het", "en", "is", "niet", "voor", "met", "een", "dat", "wanneer", "nog", "ook"},
+}
+
+// detectLanguage guesses the language of a description: by its writing
+// system, or by its most frequent stop words in Latin script. It returns ""
+// when it cannot tell.
+func detectLanguage(text string) string {
+	for _, s := range scriptLanguages {
+		if strings.IndexFunc(text, func(r rune) bool { return unicode.Is(s.table, r) }) >= 0 {
+			return s.lang
+		}
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	best, bestScore, tie := "", 0, false
+	for lang, list := range stopWords {
+		score := 0
+		for _, w := range words {
+			if containsString(list, w) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// translateTodos detects the language of every item and translates those not
+// written in the report's language. Translations of unchanged items are
+// taken from the tracker rather than made again.
+func translateTodos(todos []TodoItem, tracked []TodoItem, c TranslationConfig, tr Translator) error {
+	if c.To == "" {
+		return nil
+	}
+	known := make(map[string]TodoItem)
+	for _, t := range tracked {
+		known[t.ID] = t
+	}
+	for i := range todos {
+		t := &todos[i]
+		t.Language = detectLanguage(t.Description)
+		if t.Language == "" || t.Language == c.To || tr == nil {
+			continue
+		}
+		if old, ok := known[t.ID]; ok && old.Description == t.Description && old.Translation != "" {
+			t.Translation = old.Translation
+			continue
+		}
+		translation, err := tr.Translate(t.Description, t.Language, c.To)
+		if err != nil {
+			return fmt.Errorf("translating %s:%d: %w", repoPath(t.File), t.Line, err)
+		}
+		t.Translation = translation
+	}
+	return nil
+}
@@ -1,163 +1,228 @@
 package main
 
 import (
-	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type TodoItem struct {
-	Tag         string `json:"tag"`
-	Description string `json:"description"`
-	File        string `json:"file"`
-	Line        int    `json:"line"`
-	Date        string `json:"date"`
-}
+// stringList collects the values of a repeatable flag
+type stringList []string
 
-type TodoTracker struct {
-	Todos []TodoItem `json:"todos"`
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-var todoPattern = regexp.MustCompile(`TODO\[(\w+)\]: (.+)`)
-
-const maxFileSize = 500 * 1024 // 500 KB
-
-var blacklist = map[string]bool{
-	".action-tmp": true, // Folder itself when executing Github Action
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
-// formatSkippedFilesMarkdown returns a markdown string for skipped files
-func formatSkippedFilesMarkdown(skippedFiles []string) string {
-	if len(skippedFiles) == 0 {
-		return ""
-	}
-	var b strings.Builder
-	b.WriteString("\n# Skipped Files (larger than 500 KB)\n\n")
-	for _, file := range skippedFiles {
-		b.WriteString(fmt.Sprintf("- %s\n", file))
-	}
-	b.WriteString("\n")
-	return b.String()
+type TodoItem struct {
+	// ID identifies the item across runs; it does not change when the TODO
+	// moves to another line of the same file
+	ID string `json:"id"`
+	// Keyword is the marker that introduced the item, such as TODO or FIXME
+	Keyword string `json:"keyword,omitempty"`
+	Tag     string `json:"tag"`
+	// Severity is info, warning or error, from the keyword
+	Severity string `json:"severity,omitempty"`
+	// SuggestedTag is a tag guessed from the description and the path of
+	// an item matched without a tag, e.g. by a pattern whose tag is optional
+	SuggestedTag string `json:"suggested_tag,omitempty"`
+	Description  string `json:"description"`
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	// Column is the 1-based byte offset of the match in its line, and
+	// RuneColumn the same position counted in characters
+	Column     int    `json:"column,omitempty"`
+	RuneColumn int    `json:"rune_column,omitempty"`
+	Date       string `json:"date"`
+	// Reminder rules that already fired for this item
+	Reminders []string `json:"reminders,omitempty"`
+	// Exemption from policy enforcement, from a collecttodo:exempt annotation
+	Exemption *Exemption `json:"exemption,omitempty"`
+	// DueDate is the YYYY-MM-DD date given as [due:...] after the tag
+	DueDate string `json:"due_date,omitempty"`
+	// Expires is the YYYY-MM-DD date after which a temporary TODO must be
+	// gone, given as expires:... after the tag or in the description
+	Expires string `json:"expires,omitempty"`
+	// Priority is P0 (most urgent) to P9, given as [P0] after the tag
+	Priority string `json:"priority,omitempty"`
+	// Context is the code around the TODO, numbered, when scanned with
+	// --context
+	Context string `json:"context,omitempty"`
+	// Language is the detected language of the description, and
+	// Translation the description in the report's language; both are only
+	// set when translation is configured
+	Language    string `json:"language,omitempty"`
+	Translation string `json:"translation,omitempty"`
+	// Metadata holds the key=value pairs of a {...} block ending the
+	// description, e.g. {owner=bob, effort=3d}
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Author is the name in a Go-style TODO(name), which is also its tag
+	Author string `json:"author,omitempty"`
+	// Assignees are the users @mentioned in the description, and the author
+	Assignees []string `json:"assignees,omitempty"`
+	// References are the issues the description refers to, e.g. #123 or
+	// JIRA-456
+	References []string `json:"references,omitempty"`
+	// Ticket is the first ticket the description refers to, normalized by
+	// the ticket recognizers, and TicketURL its link; items without one are
+	// orphans
+	Ticket    string `json:"ticket,omitempty"`
+	TicketURL string `json:"ticket_url,omitempty"`
+	// Origin is where the item was introduced, and Permalink the link to it
+	// there; both are only set with --pin-permalinks
+	Origin    *Origin `json:"origin,omitempty"`
+	Permalink string  `json:"permalink,omitempty"`
 }
 
-// Checks if a path or its base name / file extension is in the ignore list
-func isInBlacklist(path string, blacklist map[string]bool) bool {
-	baseName := strings.ToLower(filepath.Base(path))
-	fileExt := strings.ToLower(filepath.Ext(path))
-	if blacklist[baseName] || blacklist[fileExt] || blacklist[path] {
-		return true
+// Label returns the keyword and tag of the item as written, e.g. FIXME[auth]
+func (t TodoItem) Label() string {
+	keyword := t.Keyword
+	if keyword == "" {
+		keyword = defaultKeyword
 	}
-	for ignore := range blacklist {
-		if ignore == "" {
-			continue
-		}
-		if strings.HasPrefix(path, ignore) {
-			return true
-		}
+	if t.Author != "" {
+		return fmt.Sprintf("%s(%s)", keyword, t.Author)
 	}
-	return false
+	return fmt.Sprintf("%s[%s]", keyword, t.Tag)
 }
 
-func scanTodos(root string, blacklist map[string]bool) ([]TodoItem, []string, error) {
-	var todos []TodoItem
-	var skippedFiles []string
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if isInBlacklist(path, blacklist) {
-			if d.IsDir() {
-				return filepath.SkipDir // Skip directory if it's in the blacklist
-			}
-			return nil // Skip file if it's in the blacklist
-		}
-
-		if d.IsDir() {
-			return nil // Continue walking directories
-		}
-
-		// Check file size before opening
-		info, err := os.Stat(path)
-		if err == nil && info.Size() > int64(maxFileSize) {
-			skippedFiles = append(skippedFiles, path)
-			return nil
-		}
-
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		buf := make([]byte, 0, maxFileSize)
-		scanner.Buffer(buf, maxFileSize)
-		lineNum := 0
-		for scanner.Scan() {
-			lineNum++
-			line := scanner.Text()
-			if matches := todoPattern.FindStringSubmatch(line); matches != nil {
-				todos = append(todos, TodoItem{
-					Tag:         matches[1],
-					Description: matches[2],
-					File:        path,
-					Line:        lineNum,
-				})
-			}
-		}
-		return scanner.Err()
-	})
-	return todos, skippedFiles, err
+type TodoTracker struct {
+	Todos []TodoItem `json:"todos"`
+	// Suppressed are the items removed with tracker rm, which scans leave
+	// out until they are restored
+	Suppressed []TodoItem `json:"suppressed,omitempty"`
 }
 
+// loadTracker reads a tracker. A missing tracker, or one that cannot be
+// decoded, is empty, so that a scan starts it over.
 func loadTracker(path string) (TodoTracker, error) {
+	return readTracker(path, false)
+}
+
+// readTracker reads a tracker file or sharded tracker; when strict, a
+// missing tracker or one that cannot be decoded is an error
+func readTracker(path string, strict bool) (TodoTracker, error) {
 	var tracker TodoTracker
+	if shardedTracker(path) {
+		var err error
+		if tracker, err = loadShards(path, strict); err != nil {
+			return TodoTracker{}, err
+		}
+		assignMissingIDs(tracker.Todos)
+		return tracker, nil
+	}
 	f, err := os.Open(path)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) && !strict {
 			return tracker, nil
 		}
 		return tracker, err
 	}
 	defer f.Close()
-	dec := json.NewDecoder(f)
-	if err := dec.Decode(&tracker); err != nil {
-		return tracker, nil // fallback to empty
+	if tracker, err = decodeTracker(f); err != nil {
+		if err == errZstd {
+			return TodoTracker{}, err
+		}
+		if strict {
+			return TodoTracker{}, fmt.Errorf("invalid tracker: %w", err)
+		}
+		return TodoTracker{}, nil // fallback to empty
 	}
+	assignMissingIDs(tracker.Todos)
 	return tracker, nil
 }
 
+// decodeTracker decodes the content of a tracker file or shard, which may be
+// gzip-compressed
+func decodeTracker(r io.Reader) (TodoTracker, error) {
+	var tracker TodoTracker
+	dr, err := decompress(r)
+	if err != nil {
+		return tracker, err
+	}
+	defer dr.Close()
+	err = json.NewDecoder(dr).Decode(&tracker)
+	return tracker, err
+}
+
+// assignMissingIDs gives IDs to the items of trackers written before IDs
+// existed
+func assignMissingIDs(todos []TodoItem) {
+	for _, t := range todos {
+		if t.ID == "" {
+			assignIDs(todos)
+			return
+		}
+	}
+}
+
 func saveTracker(path string, tracker TodoTracker) error {
-	f, err := os.Create(path)
+	if shardedTracker(path) {
+		return saveShards(path, tracker)
+	}
+	f, err := createCompressed(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 	enc := json.NewEncoder(f)
 	enc.SetIndent("", "  ")
-	return enc.Encode(tracker)
+	if err := enc.Encode(tracker); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// assignIDs gives every item its stable ID: a hash of tag, description and
+// file, plus the occurrence number when the same TODO appears several times
+// in one file. Items must be ordered by line within each file.
+func assignIDs(todos []TodoItem) {
+	seen := make(map[string]int)
+	for i, t := range todos {
+		key := fmt.Sprintf("%s|%s|%s", t.Tag, t.Description, repoPath(t.File))
+		if t.Keyword != "" && t.Keyword != defaultKeyword {
+			// TODO items keep the IDs they had before keywords existed
+			key = t.Keyword + "|" + key
+		}
+		seen[key]++
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		todos[i].ID = hex.EncodeToString(sum[:6])
+	}
 }
 
 func updateTodos(old []TodoItem, found []TodoItem, now string) []TodoItem {
-	// Map old todos by tag+desc+file+line
 	oldMap := make(map[string]TodoItem)
 	for _, t := range old {
-		key := fmt.Sprintf("%s|%s|%s|%d", t.Tag, t.Description, t.File, t.Line)
-		oldMap[key] = t
+		oldMap[t.ID] = t
 	}
+	moved := movedTodos(old, found)
 	var updated []TodoItem
 	for _, t := range found {
-		key := fmt.Sprintf("%s|%s|%s|%d", t.Tag, t.Description, t.File, t.Line)
-		if oldT, ok := oldMap[key]; ok {
+		oldT, ok := oldMap[t.ID]
+		if !ok {
+			oldT, ok = moved[t.ID]
+		}
+		if ok {
+			t.ID = oldT.ID
 			t.Date = oldT.Date
+			t.Reminders = oldT.Reminders
+			t.Origin = oldT.Origin
+			t.Permalink = oldT.Permalink
 		} else {
 			t.Date = now
 		}
@@ -166,15 +231,69 @@ func updateTodos(old []TodoItem, found []TodoItem, now string) []TodoItem {
 	return updated
 }
 
-func writeMarkdownToStdout(todos []TodoItem) {
+// movedTodos pairs the found items that are not tracked under their ID with
+// tracked items that disappeared and had the same keyword, tag and
+// description in a file that no longer exists, or in the same file: those
+// are items of a renamed file, which keep their ID and first-seen date. The
+// result maps the ID of a found item to the tracked one.
+func movedTodos(old []TodoItem, found []TodoItem) map[string]TodoItem {
+	foundIDs := make(map[string]bool)
+	for _, t := range found {
+		foundIDs[t.ID] = true
+	}
+	key := func(t TodoItem) string {
+		return t.Keyword + "\x00" + t.Tag + "\x00" + t.Description
+	}
+	gone := make(map[string][]TodoItem)
+	for _, t := range old {
+		if !foundIDs[t.ID] {
+			gone[key(t)] = append(gone[key(t)], t)
+		}
+	}
+	oldIDs := make(map[string]bool)
+	for _, t := range old {
+		oldIDs[t.ID] = true
+	}
+	moved := make(map[string]TodoItem)
+	for _, t := range found {
+		if oldIDs[t.ID] {
+			continue
+		}
+		candidates := gone[key(t)]
+		for i, oldT := range candidates {
+			if _, err := os.Stat(nativePath(oldT.File)); samePath(oldT.File, t.File) || errors.Is(err, fs.ErrNotExist) {
+				moved[t.ID] = oldT
+				gone[key(t)] = append(candidates[:i:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// formatMarkdown renders the TODO summary. When opts.Link is set, each
+// location points to the file on the code hosting service.
+func formatMarkdown(todos []TodoItem, opts markdownOptions) string {
 	var contentBuilder strings.Builder
 	contentBuilder.WriteString("# TODO Summary\n\n")
 	if len(todos) == 0 {
 		contentBuilder.WriteString("No TODOs found.\n")
 	} else {
+		// Items are grouped by tag, and by keyword within a tag; TODO groups
+		// are headed by the tag alone
+		heading := func(t TodoItem) string {
+			tag := t.Tag
+			if tag == "" {
+				tag = "Untagged"
+			}
+			if t.Keyword == "" || t.Keyword == defaultKeyword {
+				return tag
+			}
+			return fmt.Sprintf("%s (%s)", tag, t.Keyword)
+		}
 		tagMap := make(map[string][]TodoItem)
 		for _, t := range todos {
-			tagMap[t.Tag] = append(tagMap[t.Tag], t)
+			tagMap[heading(t)] = append(tagMap[heading(t)], t)
 		}
 		tags := make([]string, 0, len(tagMap))
 		for tag := range tagMap {
@@ -184,62 +303,529 @@ func writeMarkdownToStdout(todos []TodoItem) {
 		for _, tag := range tags {
 			contentBuilder.WriteString(fmt.Sprintf("## %s\n\n", tag))
 			items := tagMap[tag]
-			sort.Slice(items, func(i, j int) bool {
-				return items[i].Date < items[j].Date
-			})
-			for _, t := range items {
-				contentBuilder.WriteString(fmt.Sprintf("- **%s** (%s:%d, %s): %s\n", t.Date, filepath.Base(t.File), t.Line, t.File, t.Description))
+			if owner := opts.Owners[items[0].Tag].Team; owner != "" {
+				contentBuilder.WriteString(fmt.Sprintf("_Owner: %s_\n\n", owner))
 			}
+			sortByUrgency(items)
+			contentBuilder.WriteString(formatMarkdownItems(items, false, opts))
 			contentBuilder.WriteString("\n")
 		}
 	}
-	newSummary := contentBuilder.String()
-	fmt.Println(newSummary)
+	return contentBuilder.String()
+}
+
+// sortByUrgency orders items by priority, then severity, then oldest first
+func sortByUrgency(items []TodoItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if pi, pj := priorityRank(items[i].Priority), priorityRank(items[j].Priority); pi != pj {
+			return pi < pj
+		}
+		if si, sj := severityRank(items[i].Severity), severityRank(items[j].Severity); si != sj {
+			return si < sj
+		}
+		return items[i].Date < items[j].Date
+	})
+}
+
+// formatMarkdownItem renders one item of a summary section; label, when
+// set, is shown before the description
+func formatMarkdownItem(t TodoItem, label string, opts markdownOptions) string {
+	location := fmt.Sprintf("%s:%d", filepath.Base(t.File), t.Line)
+	if t.Permalink != "" {
+		location = fmt.Sprintf("[%s](%s)", location, t.Permalink)
+	} else if opts.Link != nil {
+		location = fmt.Sprintf("[%s](%s)", location, opts.Link(t.File, t.Line))
+	}
+	priority, due := "", ""
+	if label != "" {
+		priority = label + " "
+	}
+	if t.Priority != "" {
+		priority += fmt.Sprintf("**%s** ", t.Priority)
+	}
+	if t.Severity != "" && t.Severity != severityWarning {
+		// Warnings are the usual case and go without a badge
+		priority += fmt.Sprintf("`%s` ", t.Severity)
+	}
+	if t.DueDate != "" {
+		due = fmt.Sprintf(" _(due %s)_", t.DueDate)
+	}
+	if t.TicketURL != "" {
+		due += fmt.Sprintf(" _(ticket [%s](%s))_", t.Ticket, t.TicketURL)
+	}
+	if t.SuggestedTag != "" {
+		due += fmt.Sprintf(" _(suggested tag: %s)_", t.SuggestedTag)
+	}
+	if expired(t, opts.Now) {
+		priority = "**EXPIRED** " + priority
+		due += fmt.Sprintf(" _(expired %s)_", t.Expires)
+	} else if t.Expires != "" {
+		due += fmt.Sprintf(" _(expires %s)_", t.Expires)
+	}
+	description := linkReferences(t.Description, opts.IssueLink)
+	if t.Translation != "" {
+		description = fmt.Sprintf("%s _(%s: %s)_", linkReferences(t.Translation, opts.IssueLink), t.Language, description)
+	}
+	item := fmt.Sprintf("- **%s** (%s, %s): %s%s%s\n", t.Date, location, t.File, priority, description, due)
+	if t.Context != "" {
+		// Collapsed so that the summary stays short
+		lang := strings.TrimPrefix(filepath.Ext(t.File), ".")
+		item += fmt.Sprintf("\n  <details><summary>Code</summary>\n\n  ```%s\n%s  ```\n\n  </details>\n", lang, indentLines(t.Context, "  "))
+	}
+	return item
+}
+
+// indentLines indents every line of text
+func indentLines(text, indent string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// scanFlags registers the flags shared by every command that scans the tree.
+// Flag values are read back through applyFlags when the config is loaded.
+type scanFlags struct {
+	fs           *flag.FlagSet
+	config       *string
+	githubAction *bool
+}
+
+func addScanFlags(fs *flag.FlagSet) *scanFlags {
+	f := &scanFlags{
+		fs:           fs,
+		config:       fs.String("config", "", "Path to the JSON config file (default "+defaultConfigPath+" when present)"),
+		githubAction: fs.Bool("github-action", false, "Read the GitHub Action inputs from INPUT_* and "+actionInputsEnv),
+	}
+	fs.String("root", ".", "Comma-separated list of root directories to scan")
+	fs.String("blacklist", "", "Comma-separated list of base names/extensions/paths to ignore")
+	fs.String("whitelist", "", "Comma-separated list of base names/extensions/paths to include (overrides blacklist)")
+	fs.String("tracker", defaultTrackerPath, "Path of the tracker file")
+	fs.String("manifest", "", "Path of a manifest recording the files scanned, skipped and failed on, and the config hash")
+	fs.Var(new(stringList), "pattern", "Regular expression replacing the configured patterns, capturing the tag and description (or groups named tag and description); repeatable")
+	fs.String("keywords", "", "Comma-separated keywords collected by the default pattern, e.g. TODO,FIXME,HACK,XXX,NOTE (default TODO)")
+	fs.Bool("read-only", false, "Write nothing to disk: leave the tracker untouched and fail on any other write")
+	fs.String("tag-pattern", defaultTagPattern, "Regular expression a tag matches in the built-in patterns, without groups")
+	fs.Bool("multiline", false, "Append the indented comment lines following a TODO to its description")
+	fs.Bool("ignore-case", false, "Match the keywords in any case, e.g. todo[x]: and Todo[x]:, storing them upper-case")
+	fs.Bool("include-generated", false, "Also scan generated files, marked \"Code generated ... DO NOT EDIT\" or @generated")
+	fs.Bool("include-md-tasks", false, "Collect the unchecked tasks (- [ ] ...) of markdown files as TODOs tagged markdown")
+	fs.Bool("raw", false, "Match whole lines instead of only the comments of files in known languages")
+	fs.String("preset", "", "Comma-separated presets for common stacks: go, node, python, rust, java, monorepo")
+	fs.String("shard", "", "Only scan shard k of n of the files, e.g. 3/8; combine the shard trackers with merge-results")
+	fs.Int("max-items-per-file", 0, "Stop collecting the TODOs of a file past this many (default unlimited)")
+	fs.Int("max-items-per-dir", 0, "Stop collecting the TODOs of the files directly in a directory past this many (default unlimited)")
+	fs.String("badge", "", "Path of a shields.io endpoint file showing the number of open TODOs")
+	fs.Int("badge-yellow", defaultBadgeYellow, "Number of TODOs from which the badge is yellow")
+	fs.Int("badge-red", defaultBadgeRed, "Number of TODOs from which the badge is red")
+	return f
+}
+
+// Config loads the run configuration once the flags have been parsed
+func (f *scanFlags) Config() (Config, error) {
+	return loadConfig(f.fs, *f.config, *f.githubAction)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, p := range strings.Split(value, ",") {
+		trimmed := strings.TrimSpace(p)
+		if trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// scanAndTrack scans the tree, updates the tracker file and returns the
+// previously tracked TODOs along with the updated ones, and the warnings of
+// the scan
+func scanAndTrack(cfg Config, now string) ([]TodoItem, []TodoItem, []ScanWarning, error) {
+	scanner, err := NewScannerFromConfig(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := runHook(cfg.Hooks.PreScan, hookEvent{Event: "pre_scan", Roots: cfg.Roots, Tracker: cfg.Outputs.Tracker}); err != nil {
+		return nil, nil, nil, err
+	}
+	var found []TodoItem
+	var warnings []ScanWarning
+	manifest := newManifest(cfg, time.Now())
+	for _, root := range cfg.Roots {
+		result, err := scanner.Scan(cfg.ScanOptions(root))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scanning todos: %w", err)
+		}
+		manifest.add(result)
+		for i := range result.Todos {
+			// Outputs and the tracker use canonical paths on every platform
+			result.Todos[i].File = repoPath(result.Todos[i].File)
+		}
+		found = append(found, result.Todos...)
+		for _, w := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		warnings = append(warnings, result.Warnings...)
+	}
+
+	tracker, _ := loadTracker(cfg.Outputs.Tracker)
+	var translator Translator
+	if cfg.Translation.Command != "" {
+		translator = commandTranslator{command: cfg.Translation.Command, timeout: 30 * time.Second}
+	}
+	if err := translateTodos(found, tracker.Todos, cfg.Translation, translator); err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range tracker.Todos {
+		// Items tracked before an alias was added are carried over to the
+		// canonical tag by content, like those of renamed files
+		tracker.Todos[i].Tag = canonicalTag(cfg.TagAliases, tracker.Todos[i].Tag)
+	}
+	old, kept := tracker.Todos, []TodoItem(nil)
+	if shardedTracker(cfg.Outputs.Tracker) {
+		// Items outside the scanned roots stay as they are, so that a scan
+		// of one directory only updates its shard
+		old, kept = partitionByRoots(tracker.Todos, cfg.Roots)
+	}
+	updated := append(updateTodos(old, withoutSuppressed(found, tracker.Suppressed), now), kept...)
+	if cfg.Outputs.PinPermalinks {
+		// The configuration is validated, so the forge can be built
+		var host forge
+		if cfg.Outputs.Forge != "" {
+			host, _ = newForge(cfg.Outputs.Forge)
+		}
+		pinPermalinks(updated, host)
+	}
+	// In read-only mode the tracker is compared with but never updated
+	if !cfg.ReadOnly && !cfg.keepTracker {
+		if err := saveTracker(cfg.Outputs.Tracker, TodoTracker{Todos: updated, Suppressed: tracker.Suppressed}); err != nil {
+			return nil, nil, nil, fmt.Errorf("saving tracker: %w", err)
+		}
+	}
+	if cfg.Outputs.Manifest != "" {
+		if err := refuseWrite(cfg, "the manifest"); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := manifest.write(cfg.Outputs.Manifest); err != nil {
+			return nil, nil, nil, fmt.Errorf("saving manifest: %w", err)
+		}
+	}
+	if cfg.Outputs.Badge != "" {
+		if err := refuseWrite(cfg, "the badge"); err != nil {
+			return nil, nil, nil, err
+		}
+		// The badge counts what the summary lists
+		count := len(updated)
+		if suppressed, _ := suppressionFilter(cfg.Suppress); suppressed != nil {
+			count -= len(applyFilter(updated, suppressed, time.Now()))
+		}
+		if err := writeBadge(cfg.Outputs.Badge, count, cfg.Outputs); err != nil {
+			return nil, nil, nil, fmt.Errorf("saving badge: %w", err)
+		}
+	}
+	runPostScanHooks(cfg, buildDigest(tracker.Todos, updated, now, staleDays(cfg)), warnings)
+	return tracker.Todos, updated, warnings, nil
 }
 
 func main() {
-	// Define the --root flag
-	root := flag.String("root", ".", "Root directory to scan")
-	blacklistArg := flag.String("blacklist", "", "Comma-separated list of base names/extensions/paths to ignore")
-	whitelistArg := flag.String("whitelist", "", "Comma-separated list of base names/extensions/paths to include (overrides blacklist)")
-
-	// Parse the flags from command line
-	flag.Parse()
-
-	if *blacklistArg != "" {
-		for _, p := range strings.Split(*blacklistArg, ",") {
-			trimmed := strings.TrimSpace(p)
-			if trimmed != "" {
-				blacklist[trimmed] = true
-			}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "notify":
+			runNotify(os.Args[2:])
+			return
+		case "daemon":
+			runDaemon(os.Args[2:])
+			return
+		case "merge", "merge-results":
+			runMerge(os.Args[1], os.Args[2:])
+			return
+		case "selftest":
+			runSelftest(os.Args[2:])
+			return
+		case "pattern":
+			runPattern(os.Args[2:])
+			return
+		case "doctor":
+			runDoctor(os.Args[2:])
+			return
+		case "init":
+			runInit(os.Args[2:])
+			return
+		case "aggregate":
+			runAggregate(os.Args[2:])
+			return
+		case "push":
+			runPush(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "trend":
+			runTrend(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "pick":
+			runPick(os.Args[2:])
+			return
+		case "tracker":
+			runTracker(os.Args[2:])
+			return
+		case "search":
+			runSearch(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
 		}
 	}
-	if *whitelistArg != "" {
-		for _, p := range strings.Split(*whitelistArg, ",") {
-			trimmed := strings.TrimSpace(p)
-			if trimmed != "" {
-				blacklist[trimmed] = false
-			}
+	runSummary(os.Args[1:])
+}
+
+// addPolicyFlags defines the flags of the policies failing a run, which
+// applyFlags reads by name
+func addPolicyFlags(fs *flag.FlagSet) {
+	fs.Bool("fail-on-overdue", false, "Fail if a TODO is past the due date given as TODO[tag][due:YYYY-MM-DD]")
+	fs.Bool("fail-on-expired", false, "Fail if a TODO is past the date given as expires:YYYY-MM-DD")
+	fs.String("fail-on-severity", "", "Fail if a TODO is at least this severe: info, warning or error")
+	fs.String("valid-tags", "", "Comma-separated list of the tags TODOs may use; others are reported")
+	fs.Bool("strict-tags", false, "Fail if a TODO uses a tag missing from --valid-tags")
+	fs.Bool("no-net-increase", false, "Fail if the change adds more TODOs than it removes, compared with --base")
+	fs.String("base", "", "Tracker file or git revision to compare with (default: the pull request's target branch)")
+}
+
+// withMinPriority narrows a filter to the TODOs of a priority at least as
+// urgent as minPriority, when it is set
+func withMinPriority(only filter, minPriority string) (filter, error) {
+	if minPriority == "" {
+		return only, nil
+	}
+	urgent, err := parseMinPriority(minPriority)
+	if err != nil {
+		return nil, err
+	}
+	return func(t TodoItem, now time.Time) bool { return only(t, now) && urgent(t, now) }, nil
+}
+
+// runSummary scans the tree and prints the markdown summary
+func runSummary(args []string) {
+	fs := flag.NewFlagSet("collecttodo", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	fs.String("forge", "", "Code hosting service to publish the summary to (bitbucket)")
+	fs.String("repo-url", "", "Repository URL that #123 references link to, e.g. https://github.com/owner/repo")
+	fs.String("issue-url-template", "", "URL of an issue reference such as JIRA-456, with {id} for the reference")
+	fs.Bool("pin-permalinks", false, "Link every TODO at the commit that introduced it, found with git blame")
+	fs.Bool("commit-status", false, "Set the collecttodo/policy commit status on GitHub to the outcome of the policies")
+	fs.Var(new(stringList), "notify", "Send a digest of changes to provider:target (slack, mattermost, teams, discord, email); repeatable")
+	fs.Var(new(stringList), "route", "Send the part of the digest with the given tags to a target: tag1,tag2=provider:target; repeatable")
+	fs.Var(new(stringList), "escalate", "Page on new TODOs with the given tags: tag1,tag2=provider[:key] (pagerduty, opsgenie); repeatable")
+	filterExpr := fs.String("filter", "", "Only report, notify and enforce policies on the TODOs matching a filter expression, e.g. \"tag=security AND age>30d\"")
+	view := fs.String("view", "", "Only report, notify and enforce policies on the TODOs of a view defined in the config file")
+	groupBy := fs.String("group-by", "tag", "Section the markdown summary by tag or by assignee (@mentions)")
+	minPriority := fs.String("min-priority", "", "Only report, notify and enforce policies on the TODOs of this priority or a more urgent one, e.g. P1 for P0 and P1")
+	fs.Int("context", 0, "Number of lines of code kept before and after each TODO and shown with it in the summary")
+	cluster := fs.Float64("cluster", 0, "Group the TODOs of a section whose descriptions share at least this fraction of words, e.g. 0.6")
+	addPolicyFlags(fs)
+	countOnly := fs.Bool("count-only", false, "Only print the number of TODOs of each tag, without reading or writing the tracker")
+	outputPath := fs.String("output", "", "Write the summary to this file instead of standard output")
+	format := fs.String("format", "markdown", "Output format: "+formatNames())
+	templatePath := fs.String("template", "", "Render the report through this Go text/template file instead of --format")
+	explain := fs.String("explain-path", "", "Print whether a path is scanned and which include or exclude entry decided it, then exit")
+	fs.Parse(args)
+
+	render, err := lookupFormat(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *templatePath != "" {
+		if render, err = templateFormat(*templatePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !groupings[*groupBy] {
+		fmt.Fprintf(os.Stderr, "Error: --group-by must be tag or assignee, got %q\n", *groupBy)
+		os.Exit(1)
+	}
+	if *cluster < 0 || *cluster > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --cluster must be between 0 and 1, got %g\n", *cluster)
+		os.Exit(1)
+	}
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *explain != "" {
+		fmt.Print(explainPath(cfg.ScanOptions("."), *explain))
+		return
+	}
+	if *countOnly {
+		counts, err := countTodos(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(formatCounts(counts))
+		return
+	}
+	only, err := selectFilter(cfg, *view, *filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if only, err = withMinPriority(only, *minPriority); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --min-priority: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The configuration is validated, so building its parts cannot fail
+	var host forge
+	var link func(string, int) string
+	if cfg.Outputs.Forge != "" {
+		host, _ = newForge(cfg.Outputs.Forge)
+		link = host.Permalink
+	}
+	notifiers, _ := buildNotifiers(cfg)
+	escalations, _ := buildEscalations(cfg)
+	var status *commitStatus
+	if cfg.Outputs.CommitStatus {
+		if status, err = newCommitStatus(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --commit-status: %v\n", err)
+			os.Exit(1)
 		}
 	}
 
-	trackerPath := "todo_tracker.json"
 	now := time.Now().Format("2006-01-02")
+	old, updated, warnings, err := scanAndTrack(cfg, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+
+	violations, err := evaluatePolicies(cfg, updated, now, only)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error evaluating policies: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !cfg.Policies.StrictTags {
+		for _, t := range unknownTags(applyFilter(updated, only, time.Now()), cfg.ValidTags) {
+			fmt.Fprintf(os.Stderr, "Warning: %s:%d uses unknown tag %q\n", t.File, t.Line, t.Tag)
+		}
+	}
 
-	found, skippedFiles, err := scanTodos(*root, blacklist)
+	d := buildDigest(old, updated, now, staleDays(cfg)).filter(func(t TodoItem) bool { return only(t, time.Now()) })
+	rep := Report{
+		Todos:      applyFilter(updated, only, time.Now()),
+		New:        d.New,
+		Resolved:   d.Resolved,
+		Warnings:   warnings,
+		Violations: violations,
+		Now:        now,
+		Generated:  time.Now().UTC().Format(time.RFC3339),
+		Roots:      cfg.Roots,
+		Link:       link,
+		Base:       baseRef(cfg),
+		Owners:     cfg.Owners,
+		IssueLink:  newIssueLinker(cfg.Outputs.RepoURL, cfg.Outputs.IssueURLTemplate),
+		GroupBy:    *groupBy,
+		Cluster:    *cluster,
+		// Orphans are only worth counting once tickets are set up
+		CountOrphans: len(cfg.Tickets) > 0,
+		StaleDays:    staleDays(cfg),
+	}
+	if summarizer := summarizerFor(cfg); summarizer != nil {
+		if rep.Executive, err = summarizeTodos(rep.Todos, summarizer, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error summarizing: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if suppressed, _ := suppressionFilter(cfg.Suppress); suppressed != nil && cfg.CountSuppressed {
+		rep.Suppressed = len(applyFilter(updated, suppressed, time.Now()))
+	}
+	output, err := render(rep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+	reportPath := ""
+	if *outputPath != "" {
+		if err := refuseWrite(cfg, *outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		if err := writeCompressedFile(*outputPath, []byte(output)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
+			os.Exit(1)
+		}
+		reportPath, _ = filepath.Abs(*outputPath)
+	} else {
+		fmt.Print(output)
+	}
+	// $GITHUB_OUTPUT belongs to the runner, so it is written even in
+	// read-only mode
+	err = setActionOutputs(map[string]string{
+		"new_count":      strconv.Itoa(len(d.New)),
+		"resolved_count": strconv.Itoa(len(d.Resolved)),
+		"total":          strconv.Itoa(len(rep.Todos)),
+		"report_path":    reportPath,
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error scanning todos: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error setting action outputs: %v\n", err)
 		os.Exit(1)
 	}
 
-	tracker, _ := loadTracker(trackerPath)
-	updated := updateTodos(tracker.Todos, found, now)
-	if err := saveTracker(trackerPath, TodoTracker{Todos: updated}); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving tracker: %v\n", err)
+	if host != nil {
+		// Code hosting services always get the markdown summary
+		summary, _ := formatMarkdownReport(rep)
+		if err := host.PostSummary(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting summary: %v\n", err)
+			os.Exit(1)
+		}
+		if err := host.PostAnnotations(rep.Todos); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting annotations: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if !d.Empty() {
+		for _, n := range notifiers {
+			if err := n.Notify(d); err != nil {
+				fmt.Fprintf(os.Stderr, "Error sending notification: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	if err := escalate(escalations, d); err != nil {
+		fmt.Fprintf(os.Stderr, "Error sending alert: %v\n", err)
 		os.Exit(1)
 	}
 
-	writeMarkdownToStdout(updated)
+	if status != nil {
+		if err := status.Post(len(rep.Todos), violations); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting commit status: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	fmt.Print(formatSkippedFilesMarkdown(skippedFiles))
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "Policy %s failed: %s\n", v.policy, v.message)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
 }
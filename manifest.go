@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// scanManifest records what a run scanned and with which configuration, so
+// that two runs giving different results can be compared by diffing their
+// manifests
+type scanManifest struct {
+	GeneratedAt string `json:"generated_at"`
+	// ConfigHash is the SHA-256 of the effective configuration
+	ConfigHash string   `json:"config_hash"`
+	Roots      []string `json:"roots"`
+	Patterns   []string `json:"patterns"`
+	Todos      int      `json:"todos"`
+	Scanned    []string `json:"scanned"`
+	// Skipped are the files left out on purpose, and Errored the ones that
+	// could not be scanned completely
+	Skipped []manifestEntry `json:"skipped"`
+	Errored []manifestEntry `json:"errored"`
+}
+
+// manifestEntry is a file and why it was not scanned, or not completely
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// configHash returns the SHA-256 of the configuration in its JSON form
+func configHash(c Config) string {
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newManifest starts the manifest of a run
+func newManifest(c Config, now time.Time) *scanManifest {
+	return &scanManifest{
+		GeneratedAt: now.UTC().Format(time.RFC3339),
+		ConfigHash:  configHash(c),
+		Roots:       c.Roots,
+		Patterns:    c.Patterns,
+		Scanned:     []string{},
+		Skipped:     []manifestEntry{},
+		Errored:     []manifestEntry{},
+	}
+}
+
+// add records the files of a scan
+func (m *scanManifest) add(r ScanResult) {
+	m.Todos += len(r.Todos)
+	for _, path := range r.Scanned {
+		m.Scanned = append(m.Scanned, repoPath(path))
+	}
+	for _, path := range r.SkippedFiles {
+		m.Skipped = append(m.Skipped, manifestEntry{repoPath(path), "larger than limits.max_file_size"})
+	}
+	for _, path := range r.Generated {
+		m.Skipped = append(m.Skipped, manifestEntry{repoPath(path), "generated code"})
+	}
+	for _, path := range r.Duplicates {
+		m.Skipped = append(m.Skipped, manifestEntry{repoPath(path), "same file as another path"})
+	}
+	for _, path := range r.RefusedLinks {
+		m.Errored = append(m.Errored, manifestEntry{repoPath(path), "symbolic link outside the root"})
+	}
+	for _, cut := range r.Truncated {
+		m.Errored = append(m.Errored, manifestEntry{repoPath(cut.Path), fmt.Sprintf("%s reached: kept %d of %d TODOs", cut.Limit, cut.Kept, cut.Found)})
+	}
+	for _, slow := range r.SlowFiles {
+		m.Errored = append(m.Errored, manifestEntry{repoPath(slow.Path), fmt.Sprintf("limits.match_budget reached at line %d", slow.Line)})
+	}
+}
+
+// write saves the manifest with its file lists sorted, so that manifests of
+// the same tree are identical but for the time
+func (m *scanManifest) write(path string) error {
+	sort.Strings(m.Scanned)
+	for _, entries := range [][]manifestEntry{m.Skipped, m.Errored} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
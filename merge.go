@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// parseInterspersed parses flags that may appear before, between or after
+// the positional arguments, which it returns
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// mergeTodos unions several TODO lists. Items present in more than one list
+// are deduplicated by ID, keeping the earliest first-seen date.
+func mergeTodos(lists ...[]TodoItem) []TodoItem {
+	index := make(map[string]int)
+	var merged []TodoItem
+	for _, list := range lists {
+		for _, t := range list {
+			if i, ok := index[t.ID]; ok {
+				if t.Date != "" && (merged[i].Date == "" || t.Date < merged[i].Date) {
+					merged[i].Date = t.Date
+				}
+				continue
+			}
+			index[t.ID] = len(merged)
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
+
+// prefixTodos moves the items of a tracker under a directory, so trackers of
+// different repositories can be rolled up without their IDs colliding
+func prefixTodos(todos []TodoItem, prefix string) []TodoItem {
+	prefixed := make([]TodoItem, len(todos))
+	for i, t := range todos {
+		t.File = path.Join(prefix, repoPath(t.File))
+		prefixed[i] = t
+	}
+	assignIDs(prefixed)
+	return prefixed
+}
+
+// runMerge combines trackers, e.g. those written by sharded scans or by
+// several repositories, into one tracker and prints its summary. An input
+// given as name=path is placed under the directory name.
+func runMerge(name string, args []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	output := fs.String("o", "", "Path of the combined tracker (required)")
+	inputs := parseInterspersed(fs, args)
+	if len(inputs) == 0 || *output == "" {
+		fmt.Fprintf(os.Stderr, "Usage: collecttodo %s -o combined.json [name=]a.json [name=]b.json ...\n", name)
+		os.Exit(2)
+	}
+
+	if err := refuseWrite(envConfig(), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var lists, suppressed [][]TodoItem
+	for _, input := range inputs {
+		prefix, file, ok := strings.Cut(input, "=")
+		if !ok {
+			prefix, file = "", input
+		}
+		tracker, err := readTracker(file, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		todos, hidden := tracker.Todos, tracker.Suppressed
+		if prefix != "" {
+			todos, hidden = prefixTodos(todos, prefix), prefixTodos(hidden, prefix)
+		}
+		lists = append(lists, todos)
+		suppressed = append(suppressed, hidden)
+	}
+
+	merged := mergeTodos(lists...)
+	hidden := mergeTodos(suppressed...)
+	if err := saveTracker(*output, TodoTracker{Todos: withoutSuppressed(merged, hidden), Suppressed: hidden}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tracker: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(formatMarkdown(merged, markdownOptions{}))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// metadataBlock finds a block of key=value pairs ending a description, e.g.
+// TODO[db]: migrate schema {owner=bob, effort=3d, sprint=12}
+var metadataBlock = regexp.MustCompile(`\s*\{([^{}]*)\}\s*$`)
+
+// metadataKey is what a key of a metadata block may contain
+var metadataKey = regexp.MustCompile(`^[\w.-]+$`)
+
+// parseMetadata splits the metadata block off the end of a description. A
+// block that is not entirely made of key=value pairs is left in place, so
+// that braces in ordinary text are not mistaken for metadata.
+func parseMetadata(description string) (string, map[string]string) {
+	loc := metadataBlock.FindStringSubmatchIndex(description)
+	if loc == nil || strings.TrimSpace(description[loc[2]:loc[3]]) == "" {
+		return description, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(description[loc[2]:loc[3]], ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !metadataKey.MatchString(key) {
+			return description, nil
+		}
+		metadata[key] = strings.TrimSpace(value)
+	}
+	return description[:loc[0]], metadata
+}
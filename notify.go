@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Maximum number of items listed per section of a digest message
+const digestItemLimit = 20
+
+// digest summarizes what changed between the previous tracker and this run
+type digest struct {
+	Open     []TodoItem
+	New      []TodoItem
+	Stale    []TodoItem
+	Resolved []TodoItem
+}
+
+// Total is the number of open TODOs
+func (d digest) Total() int {
+	return len(d.Open)
+}
+
+// filter returns the part of the digest whose items satisfy keep
+func (d digest) filter(keep func(TodoItem) bool) digest {
+	pick := func(items []TodoItem) []TodoItem {
+		var kept []TodoItem
+		for _, t := range items {
+			if keep(t) {
+				kept = append(kept, t)
+			}
+		}
+		return kept
+	}
+	return digest{Open: pick(d.Open), New: pick(d.New), Stale: pick(d.Stale), Resolved: pick(d.Resolved)}
+}
+
+// Empty reports whether the digest carries no changes worth sending
+func (d digest) Empty() bool {
+	return len(d.New) == 0 && len(d.Resolved) == 0
+}
+
+// buildDigest compares the old and updated TODOs: items missing from old are
+// new, so rerunning on the same tracker reports nothing new. Known items
+// first seen more than staleDays ago are listed as stale; zero disables the
+// stale section.
+func buildDigest(old []TodoItem, updated []TodoItem, now string, staleDays int) digest {
+	d := digest{Open: updated}
+	known := make(map[string]bool)
+	for _, t := range old {
+		known[t.ID] = true
+	}
+	current := make(map[string]bool)
+	var seen []TodoItem
+	for _, t := range updated {
+		current[t.ID] = true
+		if known[t.ID] {
+			seen = append(seen, t)
+		} else {
+			d.New = append(d.New, t)
+		}
+	}
+	d.Stale = staleTodos(seen, now, staleDays)
+	for _, t := range old {
+		if !current[t.ID] {
+			d.Resolved = append(d.Resolved, t)
+		}
+	}
+	return d
+}
+
+// staleTodos returns the items first seen more than staleDays before now
+// that are not exempted; zero returns none
+func staleTodos(todos []TodoItem, now string, staleDays int) []TodoItem {
+	if staleDays <= 0 {
+		return nil
+	}
+	today, err := time.Parse("2006-01-02", now)
+	if err != nil {
+		return nil
+	}
+	staleBefore := today.AddDate(0, 0, -staleDays).Format("2006-01-02")
+	var stale []TodoItem
+	for _, t := range todos {
+		if t.Date < staleBefore && !exempt(t, now) {
+			stale = append(stale, t)
+		}
+	}
+	return stale
+}
+
+// formatDigestText renders the digest as the markdown-flavoured text shared by
+// the chat providers
+func formatDigestText(d digest) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("**TODO digest**: %d open, %d new, %d resolved\n", d.Total(), len(d.New), len(d.Resolved)))
+	writeSection := func(title string, items []TodoItem) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString(fmt.Sprintf("\n**%s**\n", title))
+		for i, t := range items {
+			if i == digestItemLimit {
+				b.WriteString(fmt.Sprintf("- ...and %d more\n", len(items)-digestItemLimit))
+				break
+			}
+			b.WriteString(fmt.Sprintf("- %s %s (%s:%d)\n", t.Label(), t.Description, t.File, t.Line))
+		}
+	}
+	writeSection("New", d.New)
+	writeSection("Stale", d.Stale)
+	writeSection("Resolved", d.Resolved)
+	return b.String()
+}
+
+// notifier delivers a digest to a chat or alerting service
+type notifier interface {
+	Notify(d digest) error
+}
+
+// newNotifier parses a provider:target specification such as teams:https://...
+// or email:a@example.com,b@example.com
+func newNotifier(spec string) (notifier, error) {
+	kind, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid notification target %q, expected provider:url", spec)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch strings.ToLower(kind) {
+	case "slack":
+		return &slackNotifier{url: target, client: client, mrkdwn: true}, nil
+	case "mattermost":
+		// Mattermost incoming webhooks accept the Slack payload with plain markdown
+		return &slackNotifier{url: target, client: client}, nil
+	case "teams":
+		return &teamsNotifier{url: target, client: client}, nil
+	case "discord":
+		return &discordNotifier{url: target, client: client}, nil
+	case "email":
+		return newEmailNotifier(target)
+	default:
+		return nil, fmt.Errorf("unknown notification provider %q", kind)
+	}
+}
+
+// buildNotifiers creates the digest destinations listed in the configuration
+func buildNotifiers(cfg Config) ([]notifier, error) {
+	var notifiers []notifier
+	for _, spec := range cfg.Outputs.Notify {
+		n, err := newNotifier(spec)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	for _, spec := range cfg.Outputs.Routes {
+		r, err := parseTagRoute(spec)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, r)
+	}
+	for _, tag := range ownedTags(cfg) {
+		contact := cfg.Owners[tag].Contact
+		if contact == "" {
+			continue
+		}
+		n, err := newNotifier(contact)
+		if err != nil {
+			return nil, fmt.Errorf("owner of %s: %v", tag, err)
+		}
+		notifiers = append(notifiers, &tagRoute{tags: map[string]bool{tag: true}, notifier: n})
+	}
+	return notifiers, nil
+}
+
+// parseTagTarget splits tag1,tag2=target into its tag set and target
+func parseTagTarget(spec string) (map[string]bool, string, error) {
+	tagList, target, ok := strings.Cut(spec, "=")
+	tags := make(map[string]bool)
+	for _, tag := range splitList(tagList) {
+		tags[tag] = true
+	}
+	if !ok || len(tags) == 0 || target == "" {
+		return nil, "", fmt.Errorf("invalid value %q, expected tag1,tag2=target", spec)
+	}
+	return tags, target, nil
+}
+
+// tagRoute forwards to its notifier only the items carrying one of its tags;
+// the tag * matches every item
+type tagRoute struct {
+	tags     map[string]bool
+	notifier notifier
+}
+
+// parseTagRoute parses tag1,tag2=provider:target, e.g. security=slack:https://...
+func parseTagRoute(spec string) (*tagRoute, error) {
+	tags, target, err := parseTagTarget(spec)
+	if err != nil {
+		return nil, err
+	}
+	n, err := newNotifier(target)
+	if err != nil {
+		return nil, err
+	}
+	return &tagRoute{tags: tags, notifier: n}, nil
+}
+
+func (r *tagRoute) Notify(d digest) error {
+	routed := d.filter(func(t TodoItem) bool {
+		return r.tags["*"] || r.tags[t.Tag]
+	})
+	if routed.Empty() && len(routed.Stale) == 0 {
+		return nil
+	}
+	return r.notifier.Notify(routed)
+}
+
+type slackNotifier struct {
+	url    string
+	client *http.Client
+	mrkdwn bool
+}
+
+func (n *slackNotifier) Notify(d digest) error {
+	text := formatDigestText(d)
+	if n.mrkdwn {
+		// Slack uses single asterisks for bold
+		text = strings.ReplaceAll(text, "**", "*")
+	}
+	return sendJSON(n.client, http.MethodPost, n.url, map[string]string{"text": text}, nil)
+}
+
+type teamsNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *teamsNotifier) Notify(d digest) error {
+	card := map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "http://schema.org/extensions",
+		"summary":  "TODO digest",
+		"text":     strings.ReplaceAll(formatDigestText(d), "\n", "\n\n"),
+	}
+	return sendJSON(n.client, http.MethodPost, n.url, card, nil)
+}
+
+// Discord limits a message to 10 embeds with at most 4096 characters of
+// description each, and 6000 characters of titles and descriptions in all
+const (
+	discordEmbedLimit       = 10
+	discordDescriptionLimit = 4096
+	discordMessageLimit     = 6000
+)
+
+// discordTagColors gives well-known tags a fixed embed color; other tags get
+// a color derived from their name so they stay stable between runs
+var discordTagColors = map[string]int{
+	"now":      0xE74C3C,
+	"urgent":   0xE74C3C,
+	"blocker":  0xE74C3C,
+	"security": 0xC0392B,
+	"fix":      0xE67E22,
+	"refactor": 0x3498DB,
+	"perf":     0x9B59B6,
+	"doc":      0x1ABC9C,
+	"test":     0x2ECC71,
+	"later":    0x95A5A6,
+	"low":      0x95A5A6,
+}
+
+func discordColor(tag string) int {
+	if c, ok := discordTagColors[strings.ToLower(tag)]; ok {
+		return c
+	}
+	h := 0
+	for _, r := range tag {
+		h = h*31 + int(r)
+	}
+	return h & 0xFFFFFF
+}
+
+type discordNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Notify sends one embed per label, such as FIXME[auth], listing the new and
+// stale TODOs with that label. Embeds that do not fit in one message are
+// sent in more messages.
+func (n *discordNotifier) Notify(d digest) error {
+	type embed struct {
+		fields map[string]interface{}
+		size   int
+	}
+	var embeds []embed
+	addEmbeds := func(kind string, items []TodoItem) {
+		byLabel := make(map[string][]TodoItem)
+		var labels []string
+		for _, t := range items {
+			label := t.Label()
+			if _, ok := byLabel[label]; !ok {
+				labels = append(labels, label)
+			}
+			byLabel[label] = append(byLabel[label], t)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			title := fmt.Sprintf("%s: %d %s", label, len(byLabel[label]), kind)
+			// The title and description of an embed fit in a message alone
+			limit := discordMessageLimit - utf8.RuneCountInString(title)
+			if limit > discordDescriptionLimit {
+				limit = discordDescriptionLimit
+			}
+			var b strings.Builder
+			size := 0
+			for _, t := range byLabel[label] {
+				line := fmt.Sprintf("- %s (`%s:%d`)\n", t.Description, t.File, t.Line)
+				width := utf8.RuneCountInString(line)
+				if size+width > limit {
+					break
+				}
+				b.WriteString(line)
+				size += width
+			}
+			embeds = append(embeds, embed{
+				fields: map[string]interface{}{
+					"title":       title,
+					"description": b.String(),
+					"color":       discordColor(byLabel[label][0].Tag),
+				},
+				size: utf8.RuneCountInString(title) + size,
+			})
+		}
+	}
+	addEmbeds("new", d.New)
+	addEmbeds("stale", d.Stale)
+
+	content := fmt.Sprintf("**TODO digest**: %d open, %d new, %d resolved", d.Total(), len(d.New), len(d.Resolved))
+	var batch []map[string]interface{}
+	size := 0
+	send := func() error {
+		payload := map[string]interface{}{"embeds": batch}
+		if content != "" {
+			// Only the first message carries the counts
+			payload["content"] = content
+			content = ""
+		}
+		batch, size = nil, 0
+		return sendJSON(n.client, http.MethodPost, n.url, payload, nil)
+	}
+	for _, e := range embeds {
+		if len(batch) == discordEmbedLimit || (len(batch) > 0 && size+e.size > discordMessageLimit) {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, e.fields)
+		size += e.size
+	}
+	if len(batch) > 0 || content != "" {
+		return send()
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ruleKind is the kind of include or exclude entry that decided a path;
+// the higher kind wins when several match
+type ruleKind int
+
+const (
+	// defaultInclude applies when no entry matches
+	defaultInclude ruleKind = iota
+	// defaultExclude entries are the built-in excludes, such as .git
+	defaultExclude
+	// explicitExclude entries come from excludes or --blacklist
+	explicitExclude
+	// explicitInclude entries come from includes or --whitelist
+	explicitInclude
+)
+
+func (k ruleKind) String() string {
+	switch k {
+	case defaultExclude:
+		return "default exclude"
+	case explicitExclude:
+		return "explicit exclude"
+	case explicitInclude:
+		return "explicit include"
+	}
+	return "default include"
+}
+
+// pathRules decides which paths a scan skips. Each entry is a base name, an
+// extension or a path prefix, except DefaultDirs: directory names, such as
+// the build of a preset, that only match whole path segments. The precedence
+// is explicit include > explicit exclude > default exclude > default include.
+type pathRules struct {
+	// Root is the scanned directory; DefaultDirs only match below it
+	Root        string
+	Defaults    []string
+	DefaultDirs []string
+	Excludes    []string
+	Includes    []string
+}
+
+// pathDecision is the fate of a path and the entry deciding it
+type pathDecision struct {
+	Skip  bool
+	Kind  ruleKind
+	Entry string
+}
+
+// entryMatches reports whether an entry names a path, by base name,
+// extension or path prefix
+func entryMatches(entry, path string) bool {
+	if entry == "" {
+		return false
+	}
+	base := filepath.Base(path)
+	return strings.EqualFold(entry, base) || strings.EqualFold(entry, filepath.Ext(path)) ||
+		strings.HasPrefix(path, filepath.ToSlash(entry))
+}
+
+// dirMatches reports whether a directory name is one of the segments of a
+// path, or the path itself when it is a directory
+func dirMatches(name, path string, isDir bool) bool {
+	parts := strings.Split(path, "/")
+	if !isDir {
+		parts = parts[:len(parts)-1]
+	}
+	for _, part := range parts {
+		if strings.EqualFold(part, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstMatch(entries []string, path string) (string, bool) {
+	for _, e := range entries {
+		if entryMatches(e, path) {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// decide returns the fate of a path. An excluded directory is still walked
+// when an include names a path inside it, so that the include can apply.
+func (r pathRules) decide(path string, isDir bool) pathDecision {
+	path = filepath.ToSlash(path)
+	if e, ok := firstMatch(r.Includes, path); ok {
+		return pathDecision{Kind: explicitInclude, Entry: e}
+	}
+	if isDir {
+		for _, e := range r.Includes {
+			if strings.HasPrefix(filepath.ToSlash(e), path+"/") {
+				return pathDecision{Kind: explicitInclude, Entry: e}
+			}
+		}
+	}
+	if e, ok := firstMatch(r.Excludes, path); ok {
+		return pathDecision{Skip: true, Kind: explicitExclude, Entry: e}
+	}
+	if e, ok := firstMatch(r.Defaults, path); ok {
+		return pathDecision{Skip: true, Kind: defaultExclude, Entry: e}
+	}
+	rel := path
+	if r.Root != "" {
+		if p, err := filepath.Rel(r.Root, path); err == nil && p != ".." && !strings.HasPrefix(p, "../") {
+			rel = filepath.ToSlash(p)
+		}
+	}
+	for _, name := range r.DefaultDirs {
+		if dirMatches(name, rel, isDir) {
+			return pathDecision{Skip: true, Kind: defaultExclude, Entry: name + "/"}
+		}
+	}
+	return pathDecision{Kind: defaultInclude}
+}
+
+func (d pathDecision) String() string {
+	if d.Kind == defaultInclude {
+		return "no entry matches (default include)"
+	}
+	verb := "included"
+	if d.Skip {
+		verb = "excluded"
+	}
+	return fmt.Sprintf("%s by %q (%s)", verb, d.Entry, d.Kind)
+}
+
+// explainPath tells whether a scan reads a path, relative to the working
+// directory like the roots, and which rule decided it: a skipped directory
+// on the way decides for everything inside it
+func explainPath(opts ScanOptions, path string) string {
+	path = filepath.Clean(path)
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if d := opts.Rules.decide(dir, true); d.Skip {
+			return fmt.Sprintf("%s: skipped, directory %s is %s\n", path, dir, d)
+		}
+	}
+	info, err := os.Stat(path)
+	isDir := err == nil && info.IsDir()
+	d := opts.Rules.decide(path, isDir)
+	switch {
+	case d.Skip:
+		return fmt.Sprintf("%s: skipped, %s\n", path, d)
+	case !isDir && opts.Extensions != nil && !opts.Extensions[strings.ToLower(filepath.Ext(path))] && !opts.Extensions[strings.ToLower(filepath.Base(path))]:
+		return fmt.Sprintf("%s: skipped, %s but not one of the extensions scanned\n", path, d)
+	}
+	return fmt.Sprintf("%s: scanned, %s\n", path, d)
+}
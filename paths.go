@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Paths are reported in one canonical form on every platform: separated by
+// slashes and relative to the working directory, which is the root of the
+// repository. repoPath converts a path to it and nativePath back; pathKey
+// also folds case where the filesystem ignores it, for comparing paths.
+
+var (
+	workDirOnce sync.Once
+	workDir     string
+	// foldCase is set when the filesystem of the working directory is
+	// case-insensitive, as usual on macOS and Windows
+	foldCase bool
+)
+
+func initWorkDir() {
+	workDirOnce.Do(func() {
+		if wd, err := os.Getwd(); err == nil {
+			workDir = wd
+			foldCase = caseInsensitive(wd)
+		}
+	})
+}
+
+// caseInsensitive reports whether a directory is on a case-insensitive
+// filesystem, by looking it up with its case swapped; a directory without
+// letters tells nothing, and the platform's default is assumed
+func caseInsensitive(dir string) bool {
+	swapped := strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, dir)
+	if swapped == dir {
+		return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	}
+	a, errA := os.Stat(dir)
+	b, errB := os.Stat(swapped)
+	return errA == nil && errB == nil && os.SameFile(a, b)
+}
+
+// repoPath returns the canonical form of a path: slash-separated, cleaned
+// and relative to the root of the repository when it is inside it
+func repoPath(path string) string {
+	if filepath.IsAbs(path) {
+		initWorkDir()
+		if rel, err := filepath.Rel(workDir, path); workDir != "" && err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			path = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+// nativePath converts a canonical path to the form of the platform, for
+// opening the file
+func nativePath(canonical string) string {
+	return filepath.FromSlash(canonical)
+}
+
+// pathKey returns the canonical form of a path for comparing it with
+// others: it is case-folded on case-insensitive filesystems, so README.md and
+// readme.md are the same file there
+func pathKey(path string) string {
+	p := repoPath(path)
+	if caseFolded() {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+// caseFolded reports whether paths differing only in case name the same
+// file
+func caseFolded() bool {
+	initWorkDir()
+	return foldCase
+}
+
+// samePath reports whether two paths name the same file
+func samePath(a, b string) bool {
+	return pathKey(a) == pathKey(b)
+}
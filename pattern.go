@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp/syntax"
+	"strings"
+)
+
+// runPattern implements the pattern command, which helps debugging custom
+// patterns without running a full scan
+func runPattern(args []string) {
+	if len(args) == 0 || (args[0] != "test" && args[0] != "explain") {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo pattern test|explain ['regex'] [--line='...']...")
+		os.Exit(2)
+	}
+	mode := args[0]
+	fs := flag.NewFlagSet("pattern "+mode, flag.ExitOnError)
+	opts := addScanFlags(fs)
+	var lines stringList
+	fs.Var(&lines, "line", "Line to match; repeatable. Without --line, test reads lines from stdin")
+	positional := parseInterspersed(fs, args[1:])
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// A pattern given on the command line replaces the configured ones
+	if len(positional) > 0 {
+		cfg.Patterns = positional
+	}
+	for _, p := range cfg.Patterns {
+		if mode == "explain" {
+			explainPattern(p)
+		}
+	}
+	scanner, err := NewScannerFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(lines) == 0 {
+		if mode == "explain" {
+			return
+		}
+		in := bufio.NewScanner(os.Stdin)
+		for in.Scan() {
+			lines = append(lines, in.Text())
+		}
+	}
+	for _, line := range lines {
+		testLine(scanner, line)
+	}
+}
+
+// testLine prints what the scanner makes of a single line, including the raw
+// captures of the first matching pattern
+func testLine(s *Scanner, line string) {
+	fmt.Printf("line:        %q\n", line)
+	t, ok := s.matchLine(line)
+	if !ok {
+		fmt.Println("result:      no match")
+		fmt.Println()
+		return
+	}
+	fmt.Printf("keyword:     %q\n", t.Keyword)
+	fmt.Printf("tag:         %q\n", t.Tag)
+	fmt.Printf("description: %q\n", t.Description)
+	for _, pattern := range s.patterns {
+		matches := pattern.re.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		fmt.Printf("pattern:     %s\n", pattern.re)
+		names := pattern.re.SubexpNames()
+		for i := 1; i < len(matches); i++ {
+			label := fmt.Sprintf("$%d", i)
+			if names[i] != "" {
+				label += " (" + names[i] + ")"
+			}
+			fmt.Printf("  %-10s %q\n", label, matches[i])
+		}
+		break
+	}
+	fmt.Println()
+}
+
+// explainPattern describes the structure of a pattern and what each capture
+// group is used for
+func explainPattern(pattern string) {
+	fmt.Printf("pattern: %s\n", pattern)
+	re, err := compilePattern(pattern)
+	if err != nil {
+		fmt.Printf("  invalid: %v\n\n", err)
+		return
+	}
+	tree, _ := syntax.Parse(pattern, syntax.Perl)
+	parts := []*syntax.Regexp{tree}
+	if tree.Op == syntax.OpConcat {
+		parts = tree.Sub
+	}
+	roles := map[int]string{1: "tag", 2: "description"}
+	if tag, description, err := patternGroups(re); err == nil {
+		roles = map[int]string{tag: "tag", description: "description"}
+	}
+	if i := re.SubexpIndex("keyword"); i > 0 {
+		roles[i] = "keyword"
+	}
+	for _, part := range parts {
+		switch part.Op {
+		case syntax.OpLiteral:
+			fmt.Printf("  literal   %q\n", string(part.Rune))
+		case syntax.OpCapture:
+			role := roles[part.Cap]
+			if role == "" {
+				role = "unused"
+			}
+			name := ""
+			if part.Name != "" {
+				name = " <" + part.Name + ">"
+			}
+			fmt.Printf("  group %d%s  %s  -> %s\n", part.Cap, name, part.Sub[0], role)
+		default:
+			fmt.Printf("  match     %s\n", part)
+		}
+	}
+	if _, _, err := patternGroups(re); err != nil {
+		fmt.Printf("  problem: %v\n", err)
+	}
+	fmt.Println(strings.Repeat("-", 40))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pickTodos draws up to count distinct items at random. When weighted, an
+// item's chance grows with its age, so forgotten TODOs come up more often.
+func pickTodos(todos []TodoItem, count int, weighted bool, now time.Time, rng *rand.Rand) []TodoItem {
+	pool := append([]TodoItem(nil), todos...)
+	var picked []TodoItem
+	for len(picked) < count && len(pool) > 0 {
+		i := rng.Intn(len(pool))
+		if weighted {
+			total := 0
+			for _, t := range pool {
+				total += ageInDays(t, now) + 1
+			}
+			n := rng.Intn(total)
+			for i = 0; n >= ageInDays(pool[i], now)+1; i++ {
+				n -= ageInDays(pool[i], now) + 1
+			}
+		}
+		picked = append(picked, pool[i])
+		pool = append(pool[:i], pool[i+1:]...)
+	}
+	return picked
+}
+
+// formatPickMarkdown renders the picked TODOs with the code around them
+func formatPickMarkdown(todos []TodoItem, now time.Time, context int) string {
+	var b strings.Builder
+	b.WriteString("# Fix-it Picks\n\n")
+	if len(todos) == 0 {
+		b.WriteString("No TODOs to pick from.\n")
+		return b.String()
+	}
+	for i, t := range todos {
+		b.WriteString(fmt.Sprintf("## %d. %s %s\n\n", i+1, t.Label(), t.Description))
+		b.WriteString(fmt.Sprintf("%s:%d, open for %d days\n", repoPath(t.File), t.Line, ageInDays(t, now)))
+		if context >= 0 {
+			if snippet, err := readContext(t.File, t.Line, context); err == nil && snippet != "" {
+				lang := strings.TrimPrefix(filepath.Ext(t.File), ".")
+				b.WriteString(fmt.Sprintf("\n```%s\n%s```\n", lang, snippet))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// formatPickText renders the picked TODOs as a chat message
+func formatPickText(todos []TodoItem, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("*Fix-it picks*: %d TODOs up for grabs\n", len(todos)))
+	for _, t := range todos {
+		b.WriteString(fmt.Sprintf("- %s %s (%s:%d, %d days old)\n", t.Label(), t.Description, t.File, t.Line, ageInDays(t, now)))
+	}
+	return b.String()
+}
+
+// runPick implements the pick command, which draws random open TODOs for
+// fix-it sessions and can post them to Slack
+func runPick(args []string) {
+	fs := flag.NewFlagSet("pick", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	tagList := fs.String("tag", "", "Comma-separated tags to pick from (default: all)")
+	count := fs.Int("count", 3, "Number of TODOs to pick")
+	weighted := fs.Bool("weighted", false, "Make older TODOs more likely to be picked")
+	seed := fs.Int64("seed", 0, "Seed of the random draw, to repeat a pick (default: random)")
+	context := fs.Int("context", 3, "Number of lines of code shown before and after each TODO")
+	slack := fs.String("slack", "", "Slack incoming webhook URL to post the picks to")
+	view := fs.String("view", "", "Only pick from the TODOs of a view defined in the config file")
+	filterExpr := fs.String("filter", "", "Only pick from the TODOs matching a filter expression")
+	parseInterspersed(fs, args)
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	only, err := selectFilter(cfg, *view, *filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+	now := time.Now()
+	tags := make(map[string]bool)
+	for _, tag := range splitList(*tagList) {
+		tags[tag] = true
+	}
+	var candidates []TodoItem
+	for _, t := range applyFilter(t.Todos, only, now) {
+		if len(tags) == 0 || tags[t.Tag] {
+			candidates = append(candidates, t)
+		}
+	}
+	if *seed == 0 {
+		*seed = now.UnixNano()
+	}
+	picked := pickTodos(candidates, *count, *weighted, now, rand.New(rand.NewSource(*seed)))
+
+	fmt.Print(formatPickMarkdown(picked, now, *context))
+	if *slack != "" && len(picked) > 0 {
+		client := &http.Client{Timeout: 30 * time.Second}
+		if err := sendJSON(client, http.MethodPost, *slack, map[string]string{"text": formatPickText(picked, now)}, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting to Slack: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// policyViolation is a policy broken by the current state of the tree; any
+// violation fails the run
+type policyViolation struct {
+	policy  string
+	message string
+}
+
+// baseRef returns the base the current change is compared with: the
+// configured one, or the target branch of the pull request being built
+func baseRef(c Config) string {
+	if c.Policies.Base != "" {
+		return c.Policies.Base
+	}
+	if ref := os.Getenv("GITHUB_BASE_REF"); ref != "" {
+		return "origin/" + ref
+	}
+	if ref := os.Getenv("BITBUCKET_PR_DESTINATION_BRANCH"); ref != "" {
+		return "origin/" + ref
+	}
+	return ""
+}
+
+// loadBaseTodos returns the TODOs of the base. The base is a tracker file,
+// or a git revision whose committed tracker is used, or which is checked out
+// in a temporary work tree and scanned when it has no tracker.
+func loadBaseTodos(c Config, base string) ([]TodoItem, error) {
+	if info, err := os.Stat(base); err == nil && !info.IsDir() {
+		tracker, err := loadTracker(base)
+		return tracker.Todos, err
+	}
+	if _, err := exec.Command("git", "rev-parse", "--verify", "--quiet", base+"^{commit}").Output(); err != nil {
+		return nil, fmt.Errorf("base %q is neither a tracker file nor a git revision (fetch it with `git fetch origin` or `fetch-depth: 0`)", base)
+	}
+	if tracker, err := loadRevisionTracker(base, c.Outputs.Tracker); err == nil {
+		return tracker.Todos, nil
+	}
+	return scanRevision(c, base)
+}
+
+// loadRevisionTracker reads the tracker committed at a git revision, like
+// readTracker in strict mode: a tracker file, gzip-compressed or not, or
+// the shards of a sharded tracker
+func loadRevisionTracker(rev, path string) (TodoTracker, error) {
+	object := rev + ":" + repoPath(path)
+	kind, err := exec.Command("git", "cat-file", "-t", object).Output()
+	if err != nil {
+		return TodoTracker{}, fmt.Errorf("no tracker at %s", object)
+	}
+	if strings.TrimSpace(string(kind)) != "tree" {
+		out, err := exec.Command("git", "show", object).Output()
+		if err != nil {
+			return TodoTracker{}, err
+		}
+		tracker, err := decodeTracker(bytes.NewReader(out))
+		if err != nil {
+			return TodoTracker{}, err
+		}
+		assignMissingIDs(tracker.Todos)
+		return tracker, nil
+	}
+	// Entries are listed as "<mode> <type> <object>\t<name>", sorted by
+	// name like the shards of loadShards
+	out, err := exec.Command("git", "ls-tree", object).Output()
+	if err != nil {
+		return TodoTracker{}, err
+	}
+	var tracker TodoTracker
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		info, name, _ := strings.Cut(line, "\t")
+		if fields := strings.Fields(info); len(fields) < 2 || fields[1] != "blob" || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := exec.Command("git", "show", object+"/"+name).Output()
+		if err != nil {
+			return TodoTracker{}, err
+		}
+		shard, err := decodeTracker(bytes.NewReader(data))
+		if err != nil {
+			return TodoTracker{}, fmt.Errorf("decoding %s/%s: %w", object, name, err)
+		}
+		tracker.Todos = append(tracker.Todos, shard.Todos...)
+		tracker.Suppressed = append(tracker.Suppressed, shard.Suppressed...)
+	}
+	assignMissingIDs(tracker.Todos)
+	return tracker, nil
+}
+
+// scanRevision scans a git revision with the current configuration
+func scanRevision(c Config, rev string) ([]TodoItem, error) {
+	if err := refuseWrite(c, "a git worktree of "+rev); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "collecttodo-base-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if out, err := exec.Command("git", "worktree", "add", "--detach", dir, rev).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("checking out %s: %s", rev, bytes.TrimSpace(out))
+	}
+	defer exec.Command("git", "worktree", "remove", "--force", dir).Run()
+
+	scanner, err := NewScannerFromConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	var todos []TodoItem
+	for _, root := range c.Roots {
+		result, err := scanner.Scan(c.ScanOptions(filepath.Join(dir, root)))
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", rev, err)
+		}
+		todos = append(todos, result.Todos...)
+	}
+	// Make the paths look like those of the working tree so that the IDs match
+	for i, t := range todos {
+		if rel, err := filepath.Rel(dir, t.File); err == nil {
+			todos[i].File = rel
+		}
+	}
+	assignIDs(todos)
+	return todos, nil
+}
+
+// graceDays returns the configured grace period in days
+func graceDays(c Config) int {
+	// Validate has already checked the age
+	days, _ := parseDays(c.Policies.GracePeriod)
+	return days
+}
+
+// staleDays returns the age after which TODOs are stale, never less than the
+// grace period
+func staleDays(c Config) int {
+	if grace := graceDays(c); c.Policies.StaleDays > 0 && grace > c.Policies.StaleDays {
+		return grace
+	}
+	return c.Policies.StaleDays
+}
+
+// checkNetIncrease fails when the change adds more TODOs than it removes
+func checkNetIncrease(base, updated []TodoItem) []policyViolation {
+	inBase := make(map[string]bool)
+	for _, t := range base {
+		inBase[t.ID] = true
+	}
+	added := 0
+	for _, t := range updated {
+		if !inBase[t.ID] {
+			added++
+		}
+	}
+	removed := len(base) - (len(updated) - added)
+	if added <= removed {
+		return nil
+	}
+	return []policyViolation{{
+		policy:  "no-net-increase",
+		message: fmt.Sprintf("this change adds %d TODOs and removes %d (net +%d); resolve %d more before merging", added, removed, added-removed, added-removed),
+	}}
+}
+
+// unknownTags returns the items whose tag is not among valid. Go-style
+// TODO(name) items are left out, since their tag is an author.
+func unknownTags(todos []TodoItem, valid []string) []TodoItem {
+	if len(valid) == 0 {
+		return nil
+	}
+	var unknown []TodoItem
+	for _, t := range todos {
+		if t.Author == "" && !containsString(valid, t.Tag) {
+			unknown = append(unknown, t)
+		}
+	}
+	return unknown
+}
+
+// checkTags reports every item using a tag missing from valid
+func checkTags(todos []TodoItem, valid []string) []policyViolation {
+	var violations []policyViolation
+	for _, t := range unknownTags(todos, valid) {
+		violations = append(violations, policyViolation{
+			policy:  "strict-tags",
+			message: fmt.Sprintf("%s:%d uses unknown tag %q; valid tags are %s", t.File, t.Line, t.Tag, strings.Join(valid, ", ")),
+		})
+	}
+	return violations
+}
+
+// evaluatePolicies checks the updated TODOs selected by only against the
+// configured policies. Exempted TODOs are left out on both sides.
+func evaluatePolicies(c Config, updated []TodoItem, now string, only filter) ([]policyViolation, error) {
+	var violations []policyViolation
+	enforced := func(todos []TodoItem) []TodoItem {
+		return applyFilter(withoutExemptions(todos, now), only, time.Now())
+	}
+	updated = enforced(updated)
+	if c.Policies.NoNetIncrease {
+		base, err := loadBaseTodos(c, baseRef(c))
+		if err != nil {
+			return nil, fmt.Errorf("loading base: %w", err)
+		}
+		violations = append(violations, checkNetIncrease(enforced(base), updated)...)
+	}
+	if c.Policies.FailOnOverdue {
+		violations = append(violations, checkOverdue(updated, now)...)
+	}
+	if c.Policies.FailOnExpired {
+		violations = append(violations, checkExpired(updated, now)...)
+	}
+	if c.Policies.FailOnSeverity != "" {
+		violations = append(violations, checkSeverity(updated, c.Policies.FailOnSeverity)...)
+	}
+	if c.Policies.StrictTags {
+		violations = append(violations, checkTags(updated, c.ValidTags)...)
+	}
+	return violations, nil
+}
+
+func formatViolationsMarkdown(violations []policyViolation) string {
+	if len(violations) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n# Policy Violations\n\n")
+	for _, v := range violations {
+		sb.WriteString(fmt.Sprintf("- **%s**: %s\n", v.policy, v.message))
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// preset bundles the settings that suit a common stack
+type preset struct {
+	// extensions are the only file types scanned; base names such as
+	// Makefile cover files without an extension
+	extensions []string
+	// excludes are the directories the stack generates
+	excludes []string
+	// detect lists files and extensions whose presence reveals the stack
+	detect []string
+}
+
+var presets = map[string]preset{
+	"go": {
+		extensions: []string{".go", ".mod", ".proto", ".md", ".yml", ".yaml", ".sh", "Makefile", "Dockerfile"},
+		excludes:   []string{"vendor", "testdata"},
+		detect:     []string{"go.mod", ".go"},
+	},
+	"node": {
+		extensions: []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".vue", ".svelte", ".css", ".scss", ".html", ".md", ".json", ".yml", ".yaml"},
+		excludes:   []string{"node_modules", "dist", "build", "coverage", ".next", ".nuxt"},
+		detect:     []string{"package.json", ".js", ".ts", ".tsx"},
+	},
+	"python": {
+		extensions: []string{".py", ".pyi", ".ipynb", ".cfg", ".toml", ".ini", ".md", ".rst", ".yml", ".yaml", ".sh"},
+		excludes:   []string{"__pycache__", ".venv", "venv", ".tox", ".mypy_cache", ".pytest_cache", "build", "dist"},
+		detect:     []string{"pyproject.toml", "requirements.txt", "setup.py", ".py"},
+	},
+	"rust": {
+		extensions: []string{".rs", ".toml", ".md"},
+		excludes:   []string{"target"},
+		detect:     []string{"Cargo.toml", ".rs"},
+	},
+	"java": {
+		extensions: []string{".java", ".kt", ".kts", ".gradle", ".xml", ".properties", ".md"},
+		excludes:   []string{"target", "build", ".gradle"},
+		detect:     []string{"pom.xml", "build.gradle", ".java", ".kt"},
+	},
+}
+
+func init() {
+	// monorepo combines every stack and skips vendored third-party code
+	var mono preset
+	for _, p := range presets {
+		mono.extensions = append(mono.extensions, p.extensions...)
+		mono.excludes = append(mono.excludes, p.excludes...)
+	}
+	mono.excludes = append(mono.excludes, "third_party", "third-party", "external")
+	presets["monorepo"] = mono
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPresets checks the named presets and, unless c already restricts the
+// extensions, adds their extensions to c. Their excludes are not added to
+// c.Excludes: ScanOptions applies them as default excludes.
+func applyPresets(c *Config) error {
+	restrict := len(c.Extensions) == 0
+	for _, name := range splitList(c.Preset) {
+		p, ok := presets[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, expected one of %s", name, strings.Join(presetNames(), ", "))
+		}
+		if restrict {
+			c.Extensions = append(c.Extensions, p.extensions...)
+		}
+	}
+	return nil
+}
+
+// presetExcludes returns the directories excluded by the named presets;
+// unknown names were rejected by applyPresets
+func presetExcludes(names string) []string {
+	var dirs []string
+	for _, name := range splitList(names) {
+		dirs = append(dirs, presets[strings.ToLower(name)].excludes...)
+	}
+	return dirs
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priorityAttribute finds a priority written between the tag and the colon
+// of a TODO, e.g. TODO[auth][P0]: tokens never expire
+var priorityAttribute = regexp.MustCompile(`\[[Pp](\d)\]`)
+
+// parsePriority returns the priority among the attributes of a TODO, such as
+// P0, or ""
+func parsePriority(attributes string) string {
+	if m := priorityAttribute.FindStringSubmatch(attributes); m != nil {
+		return "P" + m[1]
+	}
+	return ""
+}
+
+// priorityRank orders priorities: P0 is 0, P1 is 1 and so on; items without
+// a priority come after all others
+func priorityRank(priority string) int {
+	if n, err := strconv.Atoi(strings.TrimPrefix(priority, "P")); err == nil && priority != "" {
+		return n
+	}
+	return 10
+}
+
+// parseMinPriority parses a --min-priority value such as P1 into a filter
+// keeping the items of that priority or a more urgent one
+func parseMinPriority(value string) (filter, error) {
+	if len(value) != 2 || parsePriority("["+value+"]") == "" {
+		return nil, fmt.Errorf("invalid priority %q, expected P0 to P9", value)
+	}
+	rank := priorityRank(parsePriority("[" + value + "]"))
+	return func(t TodoItem, _ time.Time) bool {
+		return t.Priority != "" && priorityRank(t.Priority) <= rank
+	}, nil
+}
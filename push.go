@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitOutput runs a git command and returns its trimmed output, or "" when it
+// fails
+func gitOutput(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// repoNameFromRemote turns a remote URL such as git@github.com:acme/api.git
+// into acme/api
+func repoNameFromRemote(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	remote = strings.ReplaceAll(remote, ":", "/")
+	parts := strings.Split(remote, "/")
+	if len(parts) < 2 {
+		return remote
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+// detectRepoMetadata fills the repository name, branch and commit of a result
+// from the CI environment, falling back to the git checkout
+func detectRepoMetadata(r *scanResult) {
+	if r.Repo == "" {
+		r.Repo = firstNonEmpty(os.Getenv("GITHUB_REPOSITORY"), os.Getenv("BITBUCKET_REPO_FULL_NAME"))
+		if r.Repo == "" {
+			if remote := gitOutput("remote", "get-url", "origin"); remote != "" {
+				r.Repo = repoNameFromRemote(remote)
+			}
+		}
+	}
+	if r.Branch == "" {
+		r.Branch = firstNonEmpty(os.Getenv("GITHUB_HEAD_REF"), os.Getenv("GITHUB_REF_NAME"), os.Getenv("BITBUCKET_BRANCH"), gitOutput("rev-parse", "--abbrev-ref", "HEAD"))
+	}
+	if r.Commit == "" {
+		r.Commit = firstNonEmpty(os.Getenv("GITHUB_SHA"), os.Getenv("BITBUCKET_COMMIT"), gitOutput("rev-parse", "HEAD"))
+	}
+}
+
+// runPush implements the push command, which scans the tree and uploads the
+// result to an aggregation server
+func runPush(args []string) {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	endpoint := fs.String("endpoint", "", "Ingest URL of the aggregation server, e.g. https://todos.internal/api/ingest")
+	token := fs.String("token", os.Getenv(envPrefix+"AGGREGATE_TOKEN"), "Bearer token of the aggregation server (default $COLLECTTODO_AGGREGATE_TOKEN)")
+	repo := fs.String("repo", "", "Repository name (default: from the CI environment or the origin remote)")
+	branch := fs.String("branch", "", "Branch name (default: from the CI environment or the checkout)")
+	commit := fs.String("commit", "", "Commit hash (default: from the CI environment or the checkout)")
+	fs.Parse(args)
+
+	if *endpoint == "" {
+		fmt.Fprintln(os.Stderr, "Error: --endpoint is required")
+		os.Exit(1)
+	}
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	result := scanResult{Repo: *repo, Branch: *branch, Commit: *commit}
+	detectRepoMetadata(&result)
+	if result.Repo == "" {
+		fmt.Fprintln(os.Stderr, "Error: cannot tell the repository name; pass --repo")
+		os.Exit(1)
+	}
+
+	_, result.Todos, _, err = scanAndTrack(cfg, time.Now().Format("2006-01-02"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	err = sendJSON(client, http.MethodPost, *endpoint, result, func(req *http.Request) {
+		if *token != "" {
+			req.Header.Set("Authorization", "Bearer "+*token)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error pushing results: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Pushed %d TODOs of %s (%s) to %s\n", len(result.Todos), result.Repo, result.Branch, *endpoint)
+}
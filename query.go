@@ -0,0 +1,462 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// filter is a compiled filter expression such as
+//
+//	tag=security AND age>30d AND file~'internal/**'
+//
+// Fields are keyword, tag, description, file, line, column, age, date, id,
+// priority, severity, assignee, author, ticket and meta.<key> for the
+// metadata of an item. Operators are = and != (exact), ~ and !~ (glob on file, substring
+// elsewhere), <, <=, > and >= (numbers, ages such as 30d and dates), and
+// IN (a, b). Conditions combine with AND, OR, NOT and
+// parentheses; keywords are case-insensitive.
+type filter func(t TodoItem, now time.Time) bool
+
+// queryToken is a word, quoted string or punctuation of a filter expression
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case isQuerySpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, queryToken{text: string(c)})
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, queryToken{text: expr[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case strings.IndexByte("=!~<>", c) >= 0:
+			op := string(c)
+			if i+1 < len(expr) && (c == '!' && strings.IndexByte("=~", expr[i+1]) >= 0 || (c == '<' || c == '>') && expr[i+1] == '=') {
+				op += string(expr[i+1])
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected ! at offset %d, expected != or !~", i)
+			}
+			tokens = append(tokens, queryToken{text: op})
+			i += len(op)
+		default:
+			// A word runs to the next separator; it holds at least the rune
+			// at i, which is neither, so the loop always moves forward
+			start := i
+			_, size := utf8.DecodeRuneInString(expr[i:])
+			for i += size; i < len(expr) && !isQuerySpace(expr[i]) && strings.IndexByte("()=!~<>,'\"", expr[i]) < 0; i += size {
+				_, size = utf8.DecodeRuneInString(expr[i:])
+			}
+			tokens = append(tokens, queryToken{text: expr[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// isQuerySpace reports whether c separates words. Only ASCII whitespace
+// does: bytes such as 0xA0 are parts of UTF-8 sequences like à.
+func isQuerySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// queryParser is a recursive descent parser over the tokens of an expression
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// keyword consumes the next token if it is the given unquoted keyword
+func (p *queryParser) keyword(word string) bool {
+	if t, ok := p.peek(); ok && !t.quoted && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.keyword(text) {
+		return p.errorf("expected %q", text)
+	}
+	return nil
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	where := "at end of expression"
+	if t, ok := p.peek(); ok {
+		where = fmt.Sprintf("at %q", t.text)
+	}
+	return fmt.Errorf(format+" "+where, args...)
+}
+
+func (p *queryParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t TodoItem, now time.Time) bool { return l(t, now) || right(t, now) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t TodoItem, now time.Time) bool { return l(t, now) && right(t, now) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (filter, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(t TodoItem, now time.Time) bool { return !inner(t, now) }, nil
+	}
+	if p.keyword("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+// queryFields maps field names to the value of an item they compare
+var queryFields = map[string]func(t TodoItem, now time.Time) string{
+	"keyword": func(t TodoItem, _ time.Time) string {
+		if t.Keyword == "" {
+			return defaultKeyword
+		}
+		return t.Keyword
+	},
+	"tag":         func(t TodoItem, _ time.Time) string { return t.Tag },
+	"description": func(t TodoItem, _ time.Time) string { return t.Description },
+	"desc":        func(t TodoItem, _ time.Time) string { return t.Description },
+	"file":        func(t TodoItem, _ time.Time) string { return repoPath(t.File) },
+	"line":        func(t TodoItem, _ time.Time) string { return strconv.Itoa(t.Line) },
+	"column":      func(t TodoItem, _ time.Time) string { return strconv.Itoa(t.RuneColumn) },
+	"age":         func(t TodoItem, now time.Time) string { return strconv.Itoa(ageInDays(t, now)) },
+	"date":        func(t TodoItem, _ time.Time) string { return t.Date },
+	"id":          func(t TodoItem, _ time.Time) string { return t.ID },
+	"priority":    func(t TodoItem, _ time.Time) string { return t.Priority },
+	"severity":    func(t TodoItem, _ time.Time) string { return t.Severity },
+	"author":      func(t TodoItem, _ time.Time) string { return t.Author },
+	"ticket":      func(t TodoItem, _ time.Time) string { return t.Ticket },
+}
+
+// queryListFields are the fields holding several values; a condition holds
+// when it holds for any one of them, and an empty list compares as ""
+var queryListFields = map[string]func(t TodoItem) []string{
+	"assignee": func(t TodoItem) []string { return t.Assignees },
+}
+
+// anyValue reports whether holds is true for one of the values of a field
+func anyValue(values []string, holds func(v string) bool) bool {
+	if len(values) == 0 {
+		return holds("")
+	}
+	for _, v := range values {
+		if holds(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryParser) parseComparison() (filter, error) {
+	field, ok := p.peek()
+	if !ok || field.quoted {
+		return nil, p.errorf("expected a field name")
+	}
+	name := strings.ToLower(field.text)
+	get, known := queryFields[name]
+	var values func(t TodoItem, now time.Time) []string
+	if list, ok := queryListFields[name]; ok {
+		values, known = func(t TodoItem, _ time.Time) []string { return list(t) }, true
+	} else if key, ok := strings.CutPrefix(field.text, "meta."); ok && key != "" {
+		get, known = func(t TodoItem, _ time.Time) string { return t.Metadata[key] }, true
+	}
+	if !known {
+		return nil, p.errorf("unknown field %q (known: keyword, tag, description, file, line, column, age, date, id, priority, severity, assignee, author, ticket, meta.<key>)", field.text)
+	}
+	p.pos++
+	if values == nil {
+		values = func(t TodoItem, now time.Time) []string { return []string{get(t, now)} }
+	}
+	// some reports whether a condition holds for any value of the field
+	some := func(t TodoItem, now time.Time, holds func(v string) bool) bool {
+		return anyValue(values(t, now), holds)
+	}
+
+	if p.keyword("in") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool)
+		for {
+			v, ok := p.peek()
+			if !ok {
+				return nil, p.errorf("expected a value")
+			}
+			p.pos++
+			set[v.text] = true
+			if p.keyword(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		return func(t TodoItem, now time.Time) bool {
+			return some(t, now, func(v string) bool { return set[v] })
+		}, nil
+	}
+
+	op, ok := p.peek()
+	if !ok || op.quoted || strings.IndexByte("=!~<>", op.text[0]) < 0 {
+		return nil, p.errorf("expected an operator after %s", name)
+	}
+	p.pos++
+	v, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("expected a value")
+	}
+	p.pos++
+	value := v.text
+
+	switch op.text {
+	case "=", "!=":
+		negate := op.text == "!="
+		return func(t TodoItem, now time.Time) bool {
+			return some(t, now, func(v string) bool { return v == value }) != negate
+		}, nil
+	case "~", "!~":
+		match := func(s string) bool { return strings.Contains(s, value) }
+		if name == "file" {
+			re, err := globRegexp(value)
+			if err != nil {
+				return nil, err
+			}
+			match = re.MatchString
+		}
+		negate := op.text == "!~"
+		return func(t TodoItem, now time.Time) bool { return some(t, now, match) != negate }, nil
+	}
+
+	// Ordering operators compare numbers, or dates as strings
+	compare := func(a, b string) int { return strings.Compare(a, b) }
+	if name == "age" || name == "line" || name == "column" {
+		n, err := strconv.Atoi(value)
+		if name == "age" {
+			n, err = parseDays(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s %s: %v", name, op.text, value, err)
+		}
+		value = strconv.Itoa(n)
+		compare = func(a, b string) int {
+			x, _ := strconv.Atoi(a)
+			y, _ := strconv.Atoi(b)
+			return x - y
+		}
+	}
+	var holds func(c int) bool
+	switch op.text {
+	case "<":
+		holds = func(c int) bool { return c < 0 }
+	case "<=":
+		holds = func(c int) bool { return c <= 0 }
+	case ">":
+		holds = func(c int) bool { return c > 0 }
+	case ">=":
+		holds = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op.text)
+	}
+	return func(t TodoItem, now time.Time) bool {
+		return some(t, now, func(v string) bool { return holds(compare(v, value)) })
+	}, nil
+}
+
+// globRegexp compiles a path glob where * matches within a path segment and
+// ** across segments; case is ignored where the filesystem ignores it
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	if caseFolded() {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// parseFilter compiles a filter expression
+func parseFilter(expr string) (filter, error) {
+	tokens, err := tokenizeQuery(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %v", err)
+	}
+	if len(tokens) == 0 {
+		return func(TodoItem, time.Time) bool { return true }, nil
+	}
+	p := &queryParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = p.errorf("unexpected input")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %v", err)
+	}
+	return f, nil
+}
+
+// selectFilter combines the named view of the configuration, if any, with a
+// filter expression, if any; an item must match both, and no suppression
+// rule
+func selectFilter(c Config, view, expr string) (filter, error) {
+	f, err := parseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	// Validate has already checked the rules
+	if suppressed, _ := suppressionFilter(c.Suppress); suppressed != nil {
+		selected := f
+		f = func(t TodoItem, now time.Time) bool { return !suppressed(t, now) && selected(t, now) }
+	}
+	if view == "" {
+		return f, nil
+	}
+	viewExpr, ok := c.Views[view]
+	if !ok {
+		names := make([]string, 0, len(c.Views))
+		for name := range c.Views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown view %q (configured: %s)", view, strings.Join(names, ", "))
+	}
+	// Validate has already checked the expression
+	v, _ := parseFilter(viewExpr)
+	return func(t TodoItem, now time.Time) bool { return v(t, now) && f(t, now) }, nil
+}
+
+// applyFilter returns the items matching f
+func applyFilter(todos []TodoItem, f filter, now time.Time) []TodoItem {
+	var matched []TodoItem
+	for _, t := range todos {
+		if f(t, now) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+// runQuery implements the query command, which lists the tracked TODOs
+// matching a filter expression
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the matching items as JSON")
+	view := fs.String("view", "", "Only list the TODOs of a view defined in the config file")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) > 1 || len(positional) == 0 && *view == "" {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo query [--tracker=file] [--view=name] [--json] ['tag=security AND age>30d']")
+		os.Exit(2)
+	}
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	f, err := selectFilter(cfg, *view, strings.Join(positional, ""))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+	now := time.Now()
+	matched := applyFilter(t.Todos, f, now)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if matched == nil {
+			matched = []TodoItem{}
+		}
+		enc.Encode(matched)
+		return
+	}
+	for _, t := range matched {
+		location := fmt.Sprintf("%s:%d", t.File, t.Line)
+		if t.RuneColumn > 0 {
+			// file:line:column, which editors jump to
+			location += fmt.Sprintf(":%d", t.RuneColumn)
+		}
+		fmt.Printf("%s %s %s (%dd)\n", location, t.Label(), t.Description, ageInDays(t, now))
+	}
+}
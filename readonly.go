@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errReadOnly is wrapped by the error of every write attempted in read-only
+// mode
+var errReadOnly = errors.New("read-only mode")
+
+// refuseWrite returns an error describing the write when the configuration
+// is read-only, and nil otherwise. Everything that writes to disk checks it
+// first.
+func refuseWrite(c Config, what string) error {
+	if c.ReadOnly {
+		return fmt.Errorf("%w: refusing to write %s", errReadOnly, what)
+	}
+	return nil
+}
+
+// envConfig returns the defaults overlaid with the environment, for the
+// commands that take no configuration but must honor COLLECTTODO_READ_ONLY
+func envConfig() Config {
+	c := DefaultConfig()
+	loadConfigEnv(&c)
+	return c
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisClient is a minimal client for the Redis protocol (RESP), covering the
+// few commands used for caching and rate limiting. It keeps one connection
+// and redials after any error.
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newRedisClient parses a redis://[:password@]host[:port][/db] URL
+func newRedisClient(rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid Redis URL %q, expected redis://host:port/db", rawURL)
+	}
+	c := &redisClient{addr: u.Host}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip("AUTH", c.password); err != nil {
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip("SELECT", strconv.Itoa(c.db)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do sends a command and returns its reply: a string, an int64, nil or a
+// []interface{}
+func (c *redisClient) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(args...)
+	if _, isReplyErr := err.(redisError); err != nil && !isReplyErr {
+		// The connection is in an unknown state
+		c.close()
+	}
+	return reply, err
+}
+
+func (c *redisClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn, c.rd = nil, nil
+}
+
+func (c *redisClient) roundTrip(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisClient) readReply() (interface{}, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// Get returns the value of a key and whether it exists
+func (c *redisClient) Get(key string) (string, bool, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	s, ok := reply.(string)
+	return s, ok, nil
+}
+
+// expirySeconds rounds ttl up to the whole seconds Redis expiries take, and
+// to at least one, since Redis rejects EX 0
+func expirySeconds(ttl time.Duration) string {
+	seconds := int((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// SetEx stores a value that expires after ttl
+func (c *redisClient) SetEx(key, value string, ttl time.Duration) error {
+	_, err := c.Do("SET", key, value, "EX", expirySeconds(ttl))
+	return err
+}
+
+// Incr increments a counter, giving new counters the expiry ttl, and returns
+// its new value
+func (c *redisClient) Incr(key string, ttl time.Duration) (int64, error) {
+	reply, err := c.Do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := reply.(int64)
+	if n == 1 && ttl > 0 {
+		_, err = c.Do("EXPIRE", key, expirySeconds(ttl))
+	}
+	return n, err
+}
+
+// Keys used in Redis by the aggregation server
+const (
+	redisGenerationKey = "collecttodo:generation"
+	redisCachePrefix   = "collecttodo:cache:"
+	redisRatePrefix    = "collecttodo:rate:"
+)
+
+// responseRecorder keeps a copy of a response so that it can be cached
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// redisCache caches the responses of the read endpoints and rate-limits
+// clients. Redis failures are logged and the request is served uncached.
+type redisCache struct {
+	client    *redisClient
+	ttl       time.Duration
+	rateLimit int
+}
+
+// invalidate drops every cached response by moving to a new generation
+func (c *redisCache) invalidate() {
+	if _, err := c.client.Incr(redisGenerationKey, 0); err != nil {
+		fmt.Fprintf(os.Stderr, "Error invalidating cache: %v\n", err)
+	}
+}
+
+// allow counts a request of a client in the current minute and reports
+// whether it is within the rate limit
+func (c *redisCache) allow(client string) bool {
+	if c.rateLimit <= 0 {
+		return true
+	}
+	sum := sha1.Sum([]byte(client))
+	key := fmt.Sprintf("%s%s:%d", redisRatePrefix, hex.EncodeToString(sum[:8]), time.Now().Unix()/60)
+	n, err := c.client.Incr(key, time.Minute)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rate limiting: %v\n", err)
+		return true
+	}
+	return n <= int64(c.rateLimit)
+}
+
+// wrap rate-limits a handler per client, as identified by the client
+// function, and caches its successful GET responses for the given scope,
+// which must identify everything the response depends on besides the URL
+func (c *redisCache) wrap(client, scope func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.allow(client(r)) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		if r.Method != http.MethodGet {
+			next(w, r)
+			return
+		}
+		generation, _, err := c.client.Get(redisGenerationKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading cache: %v\n", err)
+			next(w, r)
+			return
+		}
+		sum := sha1.Sum([]byte(scope(r) + "\n" + r.URL.RequestURI()))
+		key := redisCachePrefix + generation + ":" + hex.EncodeToString(sum[:])
+		if cached, ok, err := c.client.Get(key); err == nil && ok {
+			contentType, body, _ := strings.Cut(cached, "\n")
+			w.Header().Set("Content-Type", contentType)
+			w.Write([]byte(body))
+			return
+		}
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status == http.StatusOK {
+			if err := c.client.SetEx(key, w.Header().Get("Content-Type")+"\n"+rec.body.String(), c.ttl); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing cache: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// referencePattern finds issue references in a description: #123, or a
+// tracker key such as JIRA-456. The # must start a word, so anchors in URLs
+// are not mistaken for references.
+var referencePattern = regexp.MustCompile(`(?:^|[^\w&/#])(#\d+|[A-Z][A-Z0-9]+-\d+)\b`)
+
+// parseReferences returns the issues referenced in a description, in order
+// and without duplicates
+func parseReferences(description string) []string {
+	var refs []string
+	for _, m := range referencePattern.FindAllStringSubmatch(description, -1) {
+		if !containsString(refs, m[1]) {
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// newIssueLinker returns the function linking issue references, or nil when
+// neither a repository URL nor a template is set. #123 links to the issues of
+// the repository when its URL is known; other references, and #123 without a
+// repository URL, fill the {id} of the template (123 for #123).
+func newIssueLinker(repoURL, template string) func(ref string) string {
+	if repoURL == "" && template == "" {
+		return nil
+	}
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	return func(ref string) string {
+		if number := strings.TrimPrefix(ref, "#"); number != ref {
+			if repoURL != "" {
+				return repoURL + "/issues/" + number
+			}
+			ref = number
+		}
+		if template == "" {
+			return ""
+		}
+		return strings.ReplaceAll(template, "{id}", url.PathEscape(ref))
+	}
+}
+
+// validateIssueLinks checks the --repo-url and --issue-url-template values
+func validateIssueLinks(repoURL, template string) error {
+	if repoURL != "" {
+		if u, err := url.Parse(repoURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("repo URL %q must be absolute, e.g. https://github.com/owner/repo", repoURL)
+		}
+	}
+	if template != "" && !strings.Contains(template, "{id}") {
+		return fmt.Errorf("issue URL template %q must contain {id}, e.g. https://example.atlassian.net/browse/{id}", template)
+	}
+	return nil
+}
+
+// linkReferences turns the issue references of a description into markdown
+// links
+func linkReferences(description string, issueLink func(ref string) string) string {
+	if issueLink == nil {
+		return description
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range referencePattern.FindAllStringSubmatchIndex(description, -1) {
+		start, end := m[2], m[3]
+		ref := description[start:end]
+		target := issueLink(ref)
+		if target == "" {
+			continue
+		}
+		b.WriteString(description[last:start])
+		b.WriteString(fmt.Sprintf("[%s](%s)", ref, target))
+		last = end
+	}
+	b.WriteString(description[last:])
+	return b.String()
+}
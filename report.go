@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultGoodFirstTags are the tags of TODOs meant for newcomers. They hold
+// no dash, which the default tag pattern does not allow.
+const defaultGoodFirstTags = "good_first,beginner,easy"
+
+// effortPattern finds an effort estimate in a description, such as
+// effort:2h or effort=small
+var effortPattern = regexp.MustCompile(`(?i)\beffort[:=]\s*([\w.]+)`)
+
+// lowEfforts are the size estimates small enough for a first contribution
+var lowEfforts = map[string]bool{"xs": true, "s": true, "small": true, "low": true, "trivial": true, "easy": true}
+
+// effortOf returns the effort estimate of a TODO, from its metadata or its
+// description, or ""
+func effortOf(t TodoItem) string {
+	if effort := t.Metadata["effort"]; effort != "" {
+		return effort
+	}
+	if m := effortPattern.FindStringSubmatch(t.Description); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// goodFirst reports whether a TODO suits a newcomer: it has one of the
+// good-first tags, or an effort estimate of a small size or of at most
+// maxEffort
+func goodFirst(t TodoItem, tags map[string]bool, maxEffort time.Duration) bool {
+	if tags[strings.ToLower(t.Tag)] {
+		return true
+	}
+	effort := strings.ToLower(effortOf(t))
+	if effort == "" {
+		return false
+	}
+	if lowEfforts[effort] {
+		return true
+	}
+	d, err := time.ParseDuration(effort)
+	return err == nil && d <= maxEffort
+}
+
+// readContext returns the lines of a file around a line, numbered
+func readContext(file string, line, context int) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for n := 1; scanner.Scan() && n <= line+context; n++ {
+		if n >= line-context {
+			b.WriteString(fmt.Sprintf("%4d  %s\n", n, scanner.Text()))
+		}
+	}
+	return b.String(), scanner.Err()
+}
+
+// formatGoodFirstMarkdown renders an onboarding list: every TODO with where
+// it is, what it is about and the code around it
+func formatGoodFirstMarkdown(todos []TodoItem, link func(file string, line int) string, owners map[string]TagOwner, context int) string {
+	var b strings.Builder
+	b.WriteString("# Good First TODOs\n\n")
+	if len(todos) == 0 {
+		b.WriteString("No good first TODOs right now. Tag small, self-contained tasks `good_first` or add an estimate such as `effort:1h` to list them here.\n")
+		return b.String()
+	}
+	b.WriteString("These TODOs are small, self-contained tasks and a good way to get to know the code. Pick one, read the code around it and ask the owning team if anything is unclear.\n\n")
+	for _, t := range todos {
+		b.WriteString(fmt.Sprintf("## %s\n\n", t.Description))
+		location := fmt.Sprintf("%s:%d", repoPath(t.File), t.Line)
+		if t.Permalink != "" {
+			location = fmt.Sprintf("[%s](%s)", location, t.Permalink)
+		} else if link != nil {
+			location = fmt.Sprintf("[%s](%s)", location, link(t.File, t.Line))
+		}
+		b.WriteString(fmt.Sprintf("- **Where:** %s\n", location))
+		b.WriteString(fmt.Sprintf("- **Tag:** %s\n", t.Label()))
+		if effort := effortOf(t); effort != "" {
+			b.WriteString(fmt.Sprintf("- **Effort:** %s\n", effort))
+		}
+		if owner := owners[t.Tag].Team; owner != "" {
+			b.WriteString(fmt.Sprintf("- **Ask:** %s\n", owner))
+		}
+		b.WriteString(fmt.Sprintf("- **Open since:** %s\n", t.Date))
+		if context >= 0 {
+			if snippet, err := readContext(t.File, t.Line, context); err == nil && snippet != "" {
+				lang := strings.TrimPrefix(filepath.Ext(t.File), ".")
+				b.WriteString(fmt.Sprintf("\n```%s\n%s```\n", lang, snippet))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// runReport implements the report command, which renders the tracked TODOs
+// for a particular audience; --good-first lists the ones suited to newcomers
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	fs.String("forge", "", "Code hosting service used to link each TODO (bitbucket)")
+	fs.String("repo-url", "", "Repository URL that #123 references link to, e.g. https://github.com/owner/repo")
+	fs.String("issue-url-template", "", "URL of an issue reference such as JIRA-456, with {id} for the reference")
+	goodFirstFlag := fs.Bool("good-first", false, "Only list the TODOs suited to newcomers, with the code around them")
+	tagList := fs.String("good-first-tags", defaultGoodFirstTags, "Comma-separated tags that mark TODOs for newcomers")
+	maxEffortFlag := fs.String("max-effort", "2h", "Largest effort:<duration> estimate that still suits a newcomer")
+	groupBy := fs.String("group-by", "tag", "Section the summary by tag or by assignee (@mentions)")
+	cluster := fs.Float64("cluster", 0, "Group the TODOs of a section whose descriptions share at least this fraction of words, e.g. 0.6")
+	context := fs.Int("context", 3, "Number of lines of code shown before and after each TODO")
+	view := fs.String("view", "", "Only list the TODOs of a view defined in the config file")
+	filterExpr := fs.String("filter", "", "Only list the TODOs matching a filter expression")
+	parseInterspersed(fs, args)
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	only, err := selectFilter(cfg, *view, *filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !groupings[*groupBy] {
+		fmt.Fprintf(os.Stderr, "Error: --group-by must be tag or assignee, got %q\n", *groupBy)
+		os.Exit(1)
+	}
+	if *cluster < 0 || *cluster > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --cluster must be between 0 and 1, got %g\n", *cluster)
+		os.Exit(1)
+	}
+	maxEffort, err := time.ParseDuration(*maxEffortFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --max-effort: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+	var link func(string, int) string
+	if cfg.Outputs.Forge != "" {
+		host, _ := newForge(cfg.Outputs.Forge)
+		link = host.Permalink
+	}
+	todos := applyFilter(t.Todos, only, time.Now())
+
+	if !*goodFirstFlag {
+		opts := markdownOptions{
+			Link:      link,
+			Owners:    cfg.Owners,
+			IssueLink: newIssueLinker(cfg.Outputs.RepoURL, cfg.Outputs.IssueURLTemplate),
+			Now:       time.Now().Format("2006-01-02"),
+			Cluster:   *cluster,
+		}
+		if *groupBy == "assignee" {
+			fmt.Print(formatMarkdownByAssignee(todos, opts))
+		} else {
+			fmt.Print(formatMarkdown(todos, opts))
+		}
+		return
+	}
+	tags := make(map[string]bool)
+	for _, tag := range splitList(*tagList) {
+		tags[strings.ToLower(tag)] = true
+	}
+	var selected []TodoItem
+	for _, t := range todos {
+		if !exempt(t, time.Now().Format("2006-01-02")) && goodFirst(t, tags, maxEffort) {
+			selected = append(selected, t)
+		}
+	}
+	fmt.Print(formatGoodFirstMarkdown(selected, link, cfg.Owners, *context))
+}
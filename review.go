@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// reviewComment is the payload of a pull request review comment on one line,
+// in the shape of GitHub's review comments API
+type reviewComment struct {
+	Path string `json:"path"`
+	// Position is the line's index in the file's diff, counted from the
+	// first hunk header; it is omitted when the diff is unknown
+	Position int    `json:"position,omitempty"`
+	Line     int    `json:"line"`
+	Side     string `json:"side"`
+	Body     string `json:"body"`
+}
+
+// diffPositions maps every file and new-side line of a unified diff to its
+// position in the diff, as used by GitHub review comments: the line below
+// the first hunk header of a file is position 1, and later hunk headers are
+// counted as lines too
+func diffPositions(diff string) map[string]map[int]int {
+	positions := make(map[string]map[int]int)
+	var file map[int]int
+	position, line := 0, 0
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			file = nil
+		case strings.HasPrefix(text, "+++ "):
+			path := strings.TrimPrefix(text, "+++ ")
+			if path == "/dev/null" {
+				file = nil
+				continue
+			}
+			file = make(map[int]int)
+			positions[strings.TrimPrefix(path, "b/")] = file
+			position = 0
+		case file == nil:
+		case strings.HasPrefix(text, "@@"):
+			// @@ -a,b +c,d @@: the new side starts at line c
+			if position > 0 {
+				position++
+			}
+			fields := strings.Fields(text)
+			if len(fields) >= 3 {
+				start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+				line, _ = strconv.Atoi(start)
+			}
+		case strings.HasPrefix(text, "-"):
+			position++
+		case strings.HasPrefix(text, "+"), strings.HasPrefix(text, " "):
+			position++
+			file[line] = position
+			line++
+		case strings.HasPrefix(text, `\`):
+			// "\ No newline at end of file" is part of the diff
+			position++
+		}
+	}
+	return positions
+}
+
+// formatReviewComments renders every new TODO as a review comment payload.
+// With a base revision, positions are computed from its diff with HEAD and
+// TODOs outside the diff are left out, since they cannot be commented on.
+func formatReviewComments(r Report) (string, error) {
+	var positions map[string]map[int]int
+	if r.Base != "" {
+		out, err := exec.Command("git", "diff", "--no-color", "--no-ext-diff", r.Base+"...HEAD").Output()
+		if err != nil {
+			return "", fmt.Errorf("diffing with %s: %v", r.Base, err)
+		}
+		positions = diffPositions(string(out))
+	}
+	comments := []reviewComment{}
+	for _, t := range r.New {
+		c := reviewComment{
+			Path: repoPath(t.File),
+			Line: t.Line,
+			Side: "RIGHT",
+			Body: fmt.Sprintf("New **%s**: %s", t.Label(), t.Description),
+		}
+		if positions != nil {
+			c.Position = positions[c.Path][t.Line]
+			if c.Position == 0 {
+				continue
+			}
+		}
+		comments = append(comments, c)
+	}
+	data, err := json.MarshalIndent(comments, "", "  ")
+	return string(data) + "\n", err
+}
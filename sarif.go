@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// SARIF 2.1.0 types, limited to what code scanning services read
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver sarifDriver `json:"driver"`
+	} `json:"tool"`
+	// ColumnKind tells columns are counted in characters, not UTF-16 units
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+	// Invocations carry the scan warnings as notifications
+	Invocations []sarifInvocation `json:"invocations"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations,omitempty"`
+	Descriptor struct {
+		ID string `json:"id"`
+	} `json:"descriptor"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI       string `json:"uri"`
+			URIBaseID string `json:"uriBaseId"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevels maps severities to SARIF levels
+var sarifLevels = map[string]string{"info": "note", "warning": "warning", "error": "error"}
+
+// formatSARIFReport renders the TODOs as SARIF 2.1.0 results, one rule per
+// tag, for --format=sarif
+func formatSARIFReport(r Report) (string, error) {
+	run := sarifRun{ColumnKind: "unicodeCodePoints", Results: []sarifResult{}}
+	run.Tool.Driver = sarifDriver{Name: "CollectTODO", Rules: []sarifRule{}}
+	tags := make(map[string]bool)
+	for _, t := range r.Todos {
+		rule := t.Tag
+		if rule == "" {
+			rule = "untagged"
+		}
+		tags[rule] = true
+		level := sarifLevels[t.Severity]
+		if level == "" {
+			level = "warning"
+		}
+		result := sarifResult{
+			RuleID:              rule,
+			Level:               level,
+			Message:             sarifMessage{t.Label() + ": " + t.Description},
+			PartialFingerprints: map[string]string{"collecttodo/id": t.ID},
+		}
+		loc := sarifFileLocation(t.File)
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: t.Line, StartColumn: t.RuneColumn}
+		result.Locations = []sarifLocation{loc}
+		run.Results = append(run.Results, result)
+	}
+	invocation := sarifInvocation{ExecutionSuccessful: true}
+	for _, w := range r.Warnings {
+		n := sarifNotification{Level: "warning", Message: sarifMessage{w.Message}}
+		n.Descriptor.ID = w.Kind
+		if w.Path != "" {
+			n.Locations = []sarifLocation{sarifFileLocation(w.Path)}
+		}
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, n)
+	}
+	run.Invocations = []sarifInvocation{invocation}
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	for _, tag := range names {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: tag, ShortDescription: sarifMessage{"TODOs tagged " + tag}})
+	}
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+func sarifFileLocation(file string) sarifLocation {
+	var loc sarifLocation
+	loc.PhysicalLocation.ArtifactLocation.URI = repoPath(file)
+	loc.PhysicalLocation.ArtifactLocation.URIBaseID = "%SRCROOT%"
+	return loc
+}
@@ -0,0 +1,761 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ScanOptions configures a single scan
+type ScanOptions struct {
+	// Root is the directory to walk
+	Root string
+	// Rules decide which files and directories are skipped
+	Rules pathRules
+	// Extensions, when set, are the only file extensions (or base names)
+	// scanned; keys are lower-case
+	Extensions map[string]bool
+	// Shard and Shards restrict the scan to the files of shard Shard out of
+	// Shards (1-based). Shards of 0 or 1 scans everything.
+	Shard  int
+	Shards int
+}
+
+// ScanResult holds everything found by a single scan
+type ScanResult struct {
+	Todos []TodoItem
+	// Scanned are the files read
+	Scanned      []string
+	SkippedFiles []string
+	// RefusedLinks are the symbolic links not followed because they point
+	// outside the root
+	RefusedLinks []string
+	// Duplicates are the paths not scanned because the same file, through
+	// a hard link or a bind mount, was already scanned under another path
+	Duplicates []string
+	// Generated are the files left out because they are generated code
+	Generated []string
+	// SlowFiles are the files whose matching ran out of limits.match_budget
+	SlowFiles []SlowFile
+	// Truncated are the files whose TODOs were not all collected
+	Truncated []Truncation
+	// Warnings describe every problem above, and the files that could not
+	// be read or decoded, for the reports
+	Warnings []ScanWarning
+}
+
+// Truncation records the TODOs of a file left out by an item limit
+type Truncation struct {
+	Path string
+	// Kept of the Found items were collected before Limit, the setting,
+	// stopped at Max
+	Kept, Found int
+	Limit       string
+	Max         int
+}
+
+// SlowFile is a file that was not matched to the end
+type SlowFile struct {
+	Path string
+	// Line is the first line left unmatched
+	Line int
+}
+
+// Scanner collects TODO comments from a directory tree. It is immutable once
+// built and all per-scan state lives in Scan and its options, so a single
+// Scanner can run any number of scans concurrently.
+type Scanner struct {
+	patterns      []scanPattern
+	maxFileSize   int
+	maxTotalBytes int
+	maxLineLength int
+	// maxItemsPerFile and maxItemsPerDir are 0 when unlimited
+	maxItemsPerFile int
+	maxItemsPerDir  int
+	multiline       bool
+	matchBudget     time.Duration
+	// includeGenerated scans generated files too
+	includeGenerated bool
+	// mdTasks collects the unchecked tasks of markdown files
+	mdTasks bool
+	// tagAliases maps spellings of a tag to the canonical one
+	tagAliases map[string]string
+	// context is the number of lines kept before and after each TODO
+	context int
+	// ignoreCase matches the keywords of the built-in patterns in any case
+	ignoreCase bool
+	// tickets recognize the tickets referenced by descriptions
+	tickets []ticketRecognizer
+	// severities maps upper-case keywords to their severity
+	severities map[string]string
+	// raw matches whole lines instead of only the comments of known
+	// languages
+	raw bool
+}
+
+// scanPattern is a compiled pattern and the keyword of the items it finds,
+// unless it captures the keyword in a group named keyword
+type scanPattern struct {
+	re      *regexp.Regexp
+	keyword string
+	// tag and description are the indexes of the groups capturing them
+	tag, description int
+	// author is set for the Go-style TODO(name) pattern, whose tag is the
+	// name of the author
+	author bool
+}
+
+// patternGroups returns the groups of a pattern capturing the tag and the
+// description: the groups named tag and description when it has them,
+// otherwise the first two groups
+func patternGroups(re *regexp.Regexp) (tag, description int, err error) {
+	tag, description = re.SubexpIndex("tag"), re.SubexpIndex("description")
+	if tag > 0 && description > 0 {
+		return tag, description, nil
+	}
+	if tag > 0 || description > 0 {
+		return 0, 0, fmt.Errorf("pattern %q names only one of the tag and description groups; name both or neither", re)
+	}
+	if re.NumSubexp() < 2 {
+		return 0, 0, fmt.Errorf("pattern %q must capture the tag and the description", re)
+	}
+	return 1, 2, nil
+}
+
+// compilePattern compiles a pattern with Go's RE2 syntax, which matches in
+// time linear in the input, and explains the constructs it leaves out
+func compilePattern(p string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(p)
+	var serr *syntax.Error
+	if errors.As(err, &serr) {
+		switch serr.Code {
+		case syntax.ErrInvalidPerlOp, syntax.ErrInvalidEscape, syntax.ErrInvalidRepeatOp:
+			return nil, fmt.Errorf("%w (patterns use RE2 syntax, without lookarounds, backreferences or possessive quantifiers)", err)
+		}
+	}
+	return re, err
+}
+
+// keywordExpr matches a keyword, in any case when ignoreCase is set
+func keywordExpr(keyword string, ignoreCase bool) string {
+	if ignoreCase {
+		return "(?i:" + regexp.QuoteMeta(keyword) + ")"
+	}
+	return regexp.QuoteMeta(keyword)
+}
+
+// keywordPattern returns the built-in pattern for a keyword such as FIXME,
+// whose tags match tagPattern
+func keywordPattern(keyword, tagPattern string, ignoreCase bool) string {
+	return keywordExpr(keyword, ignoreCase) + `\[((?:` + tagPattern + `))\](?:\[[^\]]*\])*: (.+)`
+}
+
+// authorPattern returns the built-in pattern for the Go convention of naming
+// the author of a TODO, as in TODO(alice): do X
+func authorPattern(keyword string, ignoreCase bool) string {
+	return keywordExpr(keyword, ignoreCase) + `\(([\p{L}\p{M}\p{N}_.@-]+)\): (.+)`
+}
+
+// NewScannerFromConfig builds a Scanner from a complete configuration
+func NewScannerFromConfig(c Config) (*Scanner, error) {
+	s := &Scanner{
+		maxFileSize:      c.Limits.MaxFileSize,
+		maxTotalBytes:    c.Limits.MaxTotalBytes,
+		maxLineLength:    c.Limits.MaxLineLength,
+		maxItemsPerFile:  c.Limits.MaxItemsPerFile,
+		maxItemsPerDir:   c.Limits.MaxItemsPerDir,
+		multiline:        c.Multiline,
+		raw:              c.Raw,
+		ignoreCase:       c.IgnoreCase,
+		context:          c.Context,
+		tagAliases:       c.TagAliases,
+		mdTasks:          c.IncludeMDTasks,
+		includeGenerated: c.IncludeGenerated,
+		severities:       severityMap(c.Severities),
+	}
+	tickets, err := compileTicketRecognizers(c.Tickets)
+	if err != nil {
+		return nil, err
+	}
+	s.tickets = tickets
+	if c.Limits.MatchBudget != "" {
+		budget, err := time.ParseDuration(c.Limits.MatchBudget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limits.match_budget: %w", err)
+		}
+		s.matchBudget = budget
+	}
+	keywords := make(map[string]string)
+	authors := make(map[string]bool)
+	patterns := c.Patterns
+	if len(patterns) == 1 && patterns[0] == defaultPattern {
+		// The keywords replace TODO in the built-in patterns; custom patterns
+		// are used as they are
+		builtin := c.Keywords
+		if len(builtin) == 0 {
+			builtin = []string{defaultKeyword}
+		}
+		patterns = nil
+		for _, k := range builtin {
+			p := keywordPattern(k, c.TagPattern, c.IgnoreCase)
+			keywords[p] = k
+			patterns = append(patterns, p)
+		}
+		for _, k := range builtin {
+			p := authorPattern(k, c.IgnoreCase)
+			keywords[p] = k
+			authors[p] = true
+			patterns = append(patterns, p)
+		}
+	}
+	for _, p := range patterns {
+		pattern, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		tag, description, err := patternGroups(pattern)
+		if err != nil {
+			return nil, err
+		}
+		keyword, ok := keywords[p]
+		if !ok {
+			keyword = defaultKeyword
+		}
+		if c.IgnoreCase {
+			// Items keep the canonical form whatever the case in the code
+			keyword = strings.ToUpper(keyword)
+		}
+		s.patterns = append(s.patterns, scanPattern{re: pattern, keyword: keyword, tag: tag, description: description, author: authors[p]})
+	}
+	return s, nil
+}
+
+// NewScanner builds a Scanner from the default configuration adjusted by opts
+func NewScanner(opts ...Option) (*Scanner, error) {
+	c := DefaultConfig()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return NewScannerFromConfig(c)
+}
+
+// shardOf deterministically assigns a file to one of n shards (1-based)
+func shardOf(path string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(repoPath(path)))
+	return int(h.Sum32()%uint32(n)) + 1
+}
+
+// parseShard parses a k/n shard specification such as 3/8
+func parseShard(spec string) (int, int, error) {
+	k, n, ok := strings.Cut(spec, "/")
+	shard, err1 := strconv.Atoi(k)
+	shards, err2 := strconv.Atoi(n)
+	if !ok || err1 != nil || err2 != nil || shards < 1 || shard < 1 || shard > shards {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected k/n with 1 <= k <= n, e.g. 3/8", spec)
+	}
+	return shard, shards, nil
+}
+
+// matchBudgetError stops the matching of a file that ran out of
+// limits.match_budget
+type matchBudgetError struct {
+	line int
+}
+
+func (e *matchBudgetError) Error() string {
+	return fmt.Sprintf("limits.match_budget reached at line %d", e.line)
+}
+
+// ignoreDirective leaves out the TODO on its line, or on the next line when
+// it is on a line of its own; disableFileDirective near the top of a file
+// leaves out the whole file
+const (
+	ignoreDirective      = "collecttodo:ignore"
+	disableFileDirective = "collecttodo:disable-file"
+)
+
+// licenseMarker recognizes the text of common license headers
+var licenseMarker = regexp.MustCompile(`(?i)copyright|licensed under|license, version|spdx-license-identifier|permission is hereby granted|gnu (lesser |affero )?general public license|mozilla public license|provided "as is"`)
+
+// licenseHeaderLines is how many lines at the top of a file may make up a
+// license header
+const licenseHeaderLines = 50
+
+// headerLine reports whether a line can be part of the comments opening a
+// file: a blank line or one starting with a comment marker
+func headerLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return true
+	}
+	for _, marker := range []string{"//", "#", "/*", "*", "--", ";", "%", "<!--", `"""`, "'''"} {
+		if strings.HasPrefix(trimmed, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// errGenerated stops the scan of a generated file
+var errGenerated = errors.New("generated file")
+
+// generatedMarker finds the markers of generated code, such as Go's
+// "// Code generated by stringer; DO NOT EDIT." or @generated
+var generatedMarker = regexp.MustCompile(`(?i)code generated .*do not edit|@generated\b`)
+
+// generatedHeaderLines is how far into a file generated-code markers are
+// looked for
+const generatedHeaderLines = 10
+
+// errByteBudget stops reading a file once the scan reached
+// limits.max_total_bytes; Scan then stops the walk and keeps what it found
+var errByteBudget = errors.New("limits.max_total_bytes reached")
+
+// withinRoot reports whether a symbolic link resolves to a file inside the
+// resolved root, so that a tree cannot make the scan read files elsewhere
+func withinRoot(root, path string) bool {
+	target, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		target, err = filepath.Abs(target)
+	}
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Scan walks opts.Root and returns the TODOs found along with the files that
+// were too large to scan. It is safe on untrusted trees: symbolic links are
+// only followed to files inside the root, the walk stops with a warning once
+// limits.max_total_bytes are read, and lines longer than limits.max_line_length are not matched,
+// which bounds the time spent on each line since patterns run in linear time.
+func (s *Scanner) Scan(opts ScanOptions) (ScanResult, error) {
+	var result ScanResult
+	root, err := filepath.EvalSymlinks(opts.Root)
+	if err != nil {
+		return result, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return result, err
+	}
+	read := 0
+	// seen holds the files scanned so far by size, to recognize the same
+	// file reached again through another path
+	seen := make(map[int64][]fs.FileInfo)
+	// perDir counts the items collected in each directory
+	perDir := make(map[string]int)
+	err = filepath.WalkDir(opts.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) && path != opts.Root {
+				result.Warnings = append(result.Warnings, newWarning(path, warnUnreadable, "%s was skipped: permission denied", repoPath(path)))
+				return nil
+			}
+			return err
+		}
+
+		if opts.Rules.decide(path, d.IsDir()).Skip {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			return nil // Continue walking directories
+		}
+
+		if opts.Extensions != nil && !opts.Extensions[strings.ToLower(filepath.Ext(path))] && !opts.Extensions[strings.ToLower(d.Name())] {
+			return nil // Not one of the file types being scanned
+		}
+
+		if opts.Shards > 1 && shardOf(path, opts.Shards) != opts.Shard {
+			return nil // Another shard scans this file
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return nil // Like WalkDir, do not follow links to directories
+			}
+			if !withinRoot(root, path) {
+				result.RefusedLinks = append(result.RefusedLinks, path)
+				result.Warnings = append(result.Warnings, newWarning(path, warnSymlink, "not following symbolic link %s, which points outside %s", repoPath(path), opts.Root))
+				return nil
+			}
+		}
+
+		// Check file size before opening
+		info, err := os.Stat(path)
+		if err == nil && info.Size() > int64(s.maxFileSize) {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			result.Warnings = append(result.Warnings, newWarning(path, warnTooLarge, "%s was skipped: larger than limits.max_file_size (%d KB)", repoPath(path), s.maxFileSize/1024))
+			return nil
+		}
+		if err == nil {
+			for _, other := range seen[info.Size()] {
+				if os.SameFile(info, other) {
+					result.Duplicates = append(result.Duplicates, path)
+					return nil
+				}
+			}
+			seen[info.Size()] = append(seen[info.Size()], info)
+		}
+
+		budget := -1
+		if s.maxTotalBytes > 0 {
+			if budget = s.maxTotalBytes - read; budget <= 0 {
+				result.Warnings = append(result.Warnings, newWarning(path, warnByteBudget, "the scan stopped before %s after limits.max_total_bytes (%d bytes); it and the files after it were not scanned", repoPath(path), s.maxTotalBytes))
+				return filepath.SkipAll
+			}
+		}
+		todos, n, warnings, err := s.scanFile(path, budget)
+		read += n
+		if errors.Is(err, errGenerated) {
+			result.Generated = append(result.Generated, path)
+			return nil
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			result.Warnings = append(result.Warnings, newWarning(path, warnUnreadable, "%s was skipped: permission denied", repoPath(path)))
+			return nil
+		}
+		result.Warnings = append(result.Warnings, warnings...)
+		result.Scanned = append(result.Scanned, path)
+		found, limit, bound := len(todos), "", 0
+		if s.maxItemsPerFile > 0 && len(todos) > s.maxItemsPerFile {
+			todos = todos[:s.maxItemsPerFile]
+			limit, bound = "limits.max_items_per_file", s.maxItemsPerFile
+		}
+		dir := filepath.Dir(path)
+		if s.maxItemsPerDir > 0 && perDir[dir]+len(todos) > s.maxItemsPerDir {
+			todos = todos[:s.maxItemsPerDir-perDir[dir]]
+			limit, bound = "limits.max_items_per_dir", s.maxItemsPerDir
+		}
+		if limit != "" {
+			result.Truncated = append(result.Truncated, Truncation{path, len(todos), found, limit, bound})
+			result.Warnings = append(result.Warnings, newWarning(path, warnTruncated, "kept %d of the %d TODOs of %s after %s (%d)", len(todos), found, repoPath(path), limit, bound))
+		}
+		perDir[dir] += len(todos)
+		result.Todos = append(result.Todos, todos...)
+		var slow *matchBudgetError
+		if errors.As(err, &slow) {
+			result.SlowFiles = append(result.SlowFiles, SlowFile{Path: path, Line: slow.line})
+			result.Warnings = append(result.Warnings, newWarning(path, warnMatchBudget, "stopped matching %s at line %d after limits.match_budget (%s); TODOs further down are missing", repoPath(path), slow.line, s.matchBudget))
+			return nil
+		}
+		if errors.Is(err, errByteBudget) {
+			result.Warnings = append(result.Warnings, newWarning(path, warnByteBudget, "the scan stopped in %s after limits.max_total_bytes (%d bytes); the rest of it and the files after it were not scanned", repoPath(path), s.maxTotalBytes))
+			return filepath.SkipAll
+		}
+		return err
+	})
+	assignIDs(result.Todos)
+	return result, err
+}
+
+// matchLine applies the patterns in order and returns the item produced by
+// the first one that matches
+func (s *Scanner) matchLine(line string) (TodoItem, bool) {
+	t, _, ok := s.matchLineAt(line)
+	return t, ok
+}
+
+// matchLineAt is matchLine that also returns where the match starts
+func (s *Scanner) matchLineAt(line string) (TodoItem, int, bool) {
+	for _, pattern := range s.patterns {
+		if loc := pattern.re.FindStringSubmatchIndex(line); loc != nil {
+			group := func(i int) string {
+				if loc[2*i] < 0 {
+					return ""
+				}
+				return line[loc[2*i]:loc[2*i+1]]
+			}
+			t := TodoItem{Keyword: pattern.keyword, Tag: group(pattern.tag)}
+			if pattern.author {
+				t.Author = t.Tag
+			}
+			t.Tag = canonicalTag(s.tagAliases, t.Tag)
+			if i := pattern.re.SubexpIndex("keyword"); i > 0 && group(i) != "" {
+				t.Keyword = group(i)
+				if s.ignoreCase {
+					t.Keyword = strings.ToUpper(t.Keyword)
+				}
+			}
+			t.Description, t.Exemption = parseExemption(group(pattern.description))
+			if start := loc[2*pattern.description]; start > loc[0] {
+				// Attributes such as [P0] or [due:2025-03-01] come before the
+				// description
+				t.DueDate = parseDue(line[loc[0]:start])
+				t.Priority = parsePriority(line[loc[0]:start])
+				_, t.Expires = parseExpires(line[loc[0]:start])
+			}
+			return t, loc[0], true
+		}
+	}
+	return TodoItem{}, 0, false
+}
+
+// matchComments matches the comments of a line found by the classifier of
+// its language, or the whole line when the language is unknown, and keeps
+// track of the block comment or string literal open across lines
+func (s *Scanner) matchComments(line string, classifier LineClassifier, open *commentState) (TodoItem, int, bool) {
+	if classifier == nil {
+		return s.matchLineAt(line)
+	}
+	var regions [][2]int
+	regions, *open = classifier.Comments(line, *open)
+	for _, r := range regions {
+		if t, start, ok := s.matchLineAt(line[r[0]:r[1]]); ok {
+			if r[1] < len(line) {
+				// Drop the space before the end of the block comment
+				t.Description = strings.TrimRight(t.Description, " \t")
+			}
+			return t, r[0] + start, true
+		}
+	}
+	return TodoItem{}, 0, false
+}
+
+// taskPattern finds an unchecked item of a markdown task list, e.g.
+// - [ ] Write the migration guide
+var taskPattern = regexp.MustCompile(`^\s*[-*+] \[ \] (.+)`)
+
+// markdownTag is the tag of the TODOs made from markdown tasks
+const markdownTag = "markdown"
+
+// matchTask makes a TODO of an unchecked markdown task
+func matchTask(line string) (TodoItem, int, bool) {
+	loc := taskPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return TodoItem{}, 0, false
+	}
+	t := TodoItem{Keyword: defaultKeyword, Tag: markdownTag}
+	t.Description, t.Exemption = parseExemption(strings.TrimSpace(line[loc[2]:loc[3]]))
+	return t, loc[2], true
+}
+
+// canonicalTag returns the tag an alias stands for, or the tag itself
+func canonicalTag(aliases map[string]string, tag string) string {
+	if canonical, ok := aliases[tag]; ok {
+		return canonical
+	}
+	return tag
+}
+
+// continuation returns the text of a line continuing a multi-line TODO:
+// a comment with the same leading whitespace and marker as the TODO's line,
+// indented further after the marker than the TODO itself, e.g.
+//
+//	// TODO[db]: migrate the schema
+//	//   once every reader is on v2
+func continuation(line, prefix string) (string, bool) {
+	marker := strings.TrimRight(prefix, " \t")
+	indent := prefix[len(marker):]
+	if strings.TrimSpace(marker) == "" || !strings.HasPrefix(line, marker) {
+		return "", false
+	}
+	rest := line[len(marker):]
+	text := strings.TrimLeft(rest, " \t")
+	if text == "" || len(rest)-len(text) <= len(indent) {
+		return "", false
+	}
+	return strings.TrimSpace(text), true
+}
+
+// scanFile collects the TODOs of a file, reading at most budget bytes unless
+// budget is negative, and returns the number of bytes read
+func (s *Scanner) scanFile(path string, budget int) ([]TodoItem, int, []ScanWarning, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer file.Close()
+	counter := &countingReader{r: file, budget: budget}
+	var todos []TodoItem
+	scanner := bufio.NewScanner(counter)
+	buf := make([]byte, 0, s.maxFileSize)
+	scanner.Buffer(buf, s.maxFileSize)
+	var classifier LineClassifier
+	if !s.raw {
+		classifier = lineClassifierFor(path)
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	mdTasks := s.mdTasks && (ext == ".md" || ext == ".markdown")
+	// open is the block comment or string literal spanning lines
+	var open commentState
+	began := time.Now()
+	// headerEnd is the last line of the comments opening the file, and
+	// license is set when they are a license header
+	headerEnd, license := 0, false
+	// ignoreNext is set by a collecttodo:ignore directive on the previous
+	// line
+	ignoreNext := false
+	// recent holds the last lines, numbered, when capturing context
+	var recent []string
+	// stoppedAt is the first line left unmatched once the budget runs out
+	stoppedAt := 0
+	// longLines counts the lines left unmatched for their length
+	longLines := 0
+	lineNum := 0
+	// prefix is the text before the last TODO while its description may
+	// continue on the next lines
+	prefix := ""
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if lineNum == 1 {
+			// Editors on Windows often start UTF-8 files with a byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if s.matchBudget > 0 && time.Since(began) > s.matchBudget {
+			stoppedAt = lineNum
+			break
+		}
+		if !s.includeGenerated && lineNum <= generatedHeaderLines && generatedMarker.MatchString(line) {
+			return nil, counter.n, nil, errGenerated
+		}
+		if s.context > 0 {
+			numbered := fmt.Sprintf("%4d  %s\n", lineNum, line)
+			for i := len(todos) - 1; i >= 0 && todos[i].Line+s.context >= lineNum; i-- {
+				todos[i].Context += numbered
+			}
+			if recent = append(recent, numbered); len(recent) > s.context+1 {
+				recent = recent[1:]
+			}
+		}
+		if lineNum <= generatedHeaderLines && strings.Contains(line, disableFileDirective) {
+			return nil, counter.n, nil, nil
+		}
+		if headerEnd == lineNum-1 && lineNum <= licenseHeaderLines && (open.inComment() || headerLine(line)) {
+			headerEnd = lineNum
+			license = license || licenseMarker.MatchString(line)
+		}
+		ignored := ignoreNext || strings.Contains(line, ignoreDirective)
+		ignoreNext = false
+		if s.maxLineLength > 0 && len(line) > s.maxLineLength {
+			// Typically minified code; not worth the matching time
+			longLines++
+			prefix = ""
+			continue
+		}
+		t, start, ok := s.matchComments(line, classifier, &open)
+		if !ok && mdTasks {
+			t, start, ok = matchTask(line)
+		}
+		// A directive on a line of its own applies to the next line
+		ignoreNext = !ok && strings.Contains(line, ignoreDirective)
+		if ok && ignored {
+			prefix = ""
+			continue
+		}
+		if ok {
+			t.File = path
+			t.Line = lineNum
+			t.Column, t.RuneColumn = start+1, utf8.RuneCountInString(line[:start])+1
+			t.Context = strings.Join(recent, "")
+			todos = append(todos, t)
+			prefix = line[:start]
+			continue
+		}
+		if s.multiline && prefix != "" {
+			if text, ok := continuation(line, prefix); ok {
+				last := &todos[len(todos)-1]
+				last.Description += " " + text
+				continue
+			}
+		}
+		prefix = ""
+	}
+	if license {
+		// TODO-like text in a license is boilerplate, not a task
+		kept := todos[:0]
+		for _, t := range todos {
+			if t.Line > headerEnd {
+				kept = append(kept, t)
+			}
+		}
+		todos = kept
+	}
+	var warnings []ScanWarning
+	if longLines > 0 {
+		warnings = append(warnings, newWarning(path, warnLongLines, "%s has %d lines longer than limits.max_line_length (%d bytes), which were not matched", repoPath(path), longLines, s.maxLineLength))
+	}
+	invalid := false
+	// Mentions, references and metadata may be on continuation lines
+	for i := range todos {
+		// Reports are UTF-8, whatever the encoding of the file
+		if !utf8.ValidString(todos[i].Description) {
+			invalid = true
+			todos[i].Description = strings.ToValidUTF8(todos[i].Description, "\uFFFD")
+		}
+		todos[i].Description, todos[i].Metadata = parseMetadata(todos[i].Description)
+		if description, date := parseExpires(todos[i].Description); date != "" {
+			todos[i].Description, todos[i].Expires = description, date
+		} else if date := todos[i].Metadata["expires"]; todos[i].Expires == "" && date != "" {
+			_, todos[i].Expires = parseExpires("expires:" + date)
+		}
+		todos[i].Assignees = parseAssignees(todos[i].Description)
+		if todos[i].Author != "" && !containsString(todos[i].Assignees, todos[i].Author) {
+			todos[i].Assignees = append([]string{todos[i].Author}, todos[i].Assignees...)
+		}
+		todos[i].References = parseReferences(todos[i].Description)
+		todos[i].Ticket, todos[i].TicketURL = findTicket(s.tickets, todos[i].Description)
+		todos[i].Severity = severityOf(s.severities, todos[i].Keyword)
+		if todos[i].Tag == "" {
+			todos[i].SuggestedTag = suggestTag(todos[i].Description, path)
+		}
+	}
+	if invalid {
+		warnings = append(warnings, newWarning(path, warnInvalidUTF8, "%s is not valid UTF-8; the invalid bytes of its TODOs were replaced with U+FFFD", repoPath(path)))
+	}
+	if stoppedAt > 0 {
+		return todos, counter.n, warnings, &matchBudgetError{line: stoppedAt}
+	}
+	if err := scanner.Err(); err != nil {
+		return todos, counter.n, warnings, err
+	}
+	if counter.exhausted {
+		return todos, counter.n, warnings, errByteBudget
+	}
+	return todos, counter.n, warnings, nil
+}
+
+// countingReader counts the bytes read through it and stops at a budget,
+// unless the budget is negative
+type countingReader struct {
+	r         io.Reader
+	n         int
+	budget    int
+	exhausted bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.budget >= 0 {
+		if c.n >= c.budget {
+			// The budget is only exhausted if there is more to read
+			var probe [1]byte
+			if n, _ := c.r.Read(probe[:]); n > 0 {
+				c.exhausted = true
+			}
+			return 0, io.EOF
+		}
+		if len(p) > c.budget-c.n {
+			p = p[:c.budget-c.n]
+		}
+	}
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// scanFiles scans a directory holding the given files and returns the TODOs
+// found, by base name of their file
+func scanFiles(t *testing.T, files map[string]string) map[string][]TodoItem {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := DefaultConfig()
+	c.Roots = []string{dir}
+	s, err := NewScannerFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Scan(c.ScanOptions(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string][]TodoItem)
+	for _, todo := range result.Todos {
+		found[filepath.Base(todo.File)] = append(found[filepath.Base(todo.File)], todo)
+	}
+	return found
+}
+
+func TestScanMultibyteTags(t *testing.T) {
+	tests := []struct {
+		name, content, tag, description string
+	}{
+		{"cyrillic.go", "package x\n\n// TODO[ошибка]: починить разбор\n", "ошибка", "починить разбор"},
+		{"cjk.py", "x = 1  # TODO[性能]: 缓存结果\n", "性能", "缓存结果"},
+		{"combining.js", "// TODO[cafe\u0301]: accent written as a combining mark\n", "cafe\u0301", "accent written as a combining mark"},
+	}
+	files := make(map[string]string)
+	for _, tt := range tests {
+		files[tt.name] = tt.content
+	}
+	found := scanFiles(t, files)
+	for _, tt := range tests {
+		todos := found[tt.name]
+		if len(todos) != 1 {
+			t.Errorf("%s: found %d TODOs, want 1", tt.name, len(todos))
+			continue
+		}
+		if todos[0].Tag != tt.tag || todos[0].Description != tt.description {
+			t.Errorf("%s: got tag %q and description %q, want %q and %q", tt.name, todos[0].Tag, todos[0].Description, tt.tag, tt.description)
+		}
+	}
+}
+
+func TestScanRuneColumn(t *testing.T) {
+	line := `s := "ñandú — 日本" // TODO[x]: y`
+	found := scanFiles(t, map[string]string{"a.go": line + "\n"})
+	todos := found["a.go"]
+	if len(todos) != 1 {
+		t.Fatalf("found %d TODOs, want 1", len(todos))
+	}
+	offset := strings.Index(line, "TODO")
+	wantColumn, wantRuneColumn := offset+1, utf8.RuneCountInString(line[:offset])+1
+	if wantColumn == wantRuneColumn {
+		t.Fatal("the line must have multibyte characters before the keyword")
+	}
+	if todos[0].Column != wantColumn || todos[0].RuneColumn != wantRuneColumn {
+		t.Errorf("got column %d and rune column %d, want %d and %d", todos[0].Column, todos[0].RuneColumn, wantColumn, wantRuneColumn)
+	}
+}
+
+func TestScanByteOrderMark(t *testing.T) {
+	found := scanFiles(t, map[string]string{
+		"bom.go":      "\ufeff// TODO[bom]: first line\npackage x\n",
+		"bom_raw.txt": "\ufeffTODO[raw]: first line of an unknown language\n",
+	})
+	tests := []struct {
+		file, tag, description string
+	}{
+		{"bom.go", "bom", "first line"},
+		{"bom_raw.txt", "raw", "first line of an unknown language"},
+	}
+	for _, tt := range tests {
+		todos := found[tt.file]
+		if len(todos) != 1 {
+			t.Errorf("%s: found %d TODOs, want 1", tt.file, len(todos))
+			continue
+		}
+		got := todos[0]
+		if got.Tag != tt.tag || got.Description != tt.description || got.Line != 1 {
+			t.Errorf("%s: got %s %q on line %d, want tag %q and %q on line 1", tt.file, got.Label(), got.Description, got.Line, tt.tag, tt.description)
+		}
+		// Columns count from the first character after the byte order mark
+		if got.Column != got.RuneColumn {
+			t.Errorf("%s: got column %d and rune column %d, want them equal", tt.file, got.Column, got.RuneColumn)
+		}
+	}
+}
+
+func TestScanByteBudget(t *testing.T) {
+	dir := t.TempDir()
+	// Files are walked in lexical order: a.go fits in the budget, b.go is
+	// cut short, and c.go is not read
+	files := map[string]string{
+		"a.go": "// TODO[a]: first\n",
+		"b.go": "// TODO[b]: second\n" + strings.Repeat("// filler\n", 10) + "// TODO[b]: missed\n",
+		"c.go": "// TODO[c]: third\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := DefaultConfig()
+	c.Limits.MaxTotalBytes = len(files["a.go"]) + 40
+	s, err := NewScannerFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Scan(c.ScanOptions(dir))
+	if err != nil {
+		t.Fatalf("Scan: %v, want the partial results", err)
+	}
+	var descriptions []string
+	for _, todo := range result.Todos {
+		descriptions = append(descriptions, todo.Description)
+	}
+	if got := strings.Join(descriptions, ","); got != "first,second" {
+		t.Errorf("got TODOs %q, want first,second", got)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Kind != warnByteBudget || filepath.Base(result.Warnings[0].Path) != "b.go" {
+		t.Errorf("got warnings %v, want one byte-budget warning on b.go", result.Warnings)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Search result limits of /api/search
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 1000
+)
+
+// searchIndex is an in-memory full-text index over the descriptions and
+// file paths of the latest results of every namespace. Query words match
+// indexed words exactly, as a prefix, or with a typo or two.
+type searchIndex struct {
+	mu         sync.RWMutex
+	namespaces map[string]*indexedNamespace
+	// vocabulary counts the namespaces using each word, so that a query word
+	// is expanded once for all of them
+	vocabulary map[string]int
+}
+
+// indexedNamespace maps the words of a namespace to its items
+type indexedNamespace struct {
+	repo  string
+	todos []TodoItem
+	words map[string][]int
+}
+
+// searchHit is an item found by a search, best matches first
+type searchHit struct {
+	repoTodo
+	Score int `json:"score"`
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{namespaces: make(map[string]*indexedNamespace), vocabulary: make(map[string]int)}
+}
+
+// searchWords splits text into lower-case words; paths are split at
+// slashes, dots, dashes and underscores too
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Put indexes a result, replacing the previous one of its namespace
+func (x *searchIndex) Put(r scanResult) {
+	n := &indexedNamespace{repo: r.Repo, todos: r.Todos, words: make(map[string][]int)}
+	for i, t := range r.Todos {
+		for _, w := range append(searchWords(t.Description), searchWords(repoPath(t.File))...) {
+			if postings := n.words[w]; len(postings) == 0 || postings[len(postings)-1] != i {
+				n.words[w] = append(postings, i)
+			}
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(r.Namespace)
+	x.namespaces[r.Namespace] = n
+	for w := range n.words {
+		x.vocabulary[w]++
+	}
+}
+
+// Remove drops a namespace from the index
+func (x *searchIndex) Remove(namespace string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(namespace)
+}
+
+func (x *searchIndex) remove(namespace string) {
+	old, ok := x.namespaces[namespace]
+	if !ok {
+		return
+	}
+	for w := range old.words {
+		if x.vocabulary[w]--; x.vocabulary[w] == 0 {
+			delete(x.vocabulary, w)
+		}
+	}
+	delete(x.namespaces, namespace)
+}
+
+// expand returns the indexed words a query word matches, with their weight:
+// 3 for the word itself, 2 for words it is a prefix of, and 1 for words
+// within the edit distance allowed for its length
+func (x *searchIndex) expand(q string) map[string]int {
+	matches := make(map[string]int)
+	maxDistance := 0
+	switch n := len([]rune(q)); {
+	case n >= 8:
+		maxDistance = 2
+	case n >= 4:
+		maxDistance = 1
+	}
+	for w := range x.vocabulary {
+		switch {
+		case w == q:
+			matches[w] = 3
+		case strings.HasPrefix(w, q):
+			matches[w] = 2
+		case maxDistance > 0 && editDistance(q, w, maxDistance) <= maxDistance:
+			matches[w] = 1
+		}
+	}
+	return matches
+}
+
+// Search returns the items of the namespaces under the prefixes matching
+// every word of the query, best matches first, at most limit of them
+func (x *searchIndex) Search(query string, prefixes []string, limit int) []searchHit {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return []searchHit{}
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	expanded := make([]map[string]int, len(words))
+	for i, w := range words {
+		expanded[i] = x.expand(w)
+	}
+	hits := []searchHit{}
+	for ns, n := range x.namespaces {
+		visible := false
+		for _, prefix := range prefixes {
+			visible = visible || inNamespace(ns, prefix)
+		}
+		if !visible {
+			continue
+		}
+		// scores holds the best weight of every query word for each item;
+		// items missing a word are dropped
+		var scores map[int]int
+		for _, matches := range expanded {
+			best := make(map[int]int)
+			for w, weight := range matches {
+				for _, i := range n.words[w] {
+					if weight > best[i] {
+						best[i] = weight
+					}
+				}
+			}
+			if scores == nil {
+				scores = best
+				continue
+			}
+			for i, s := range scores {
+				if b, ok := best[i]; ok {
+					scores[i] = s + b
+				} else {
+					delete(scores, i)
+				}
+			}
+		}
+		for i, score := range scores {
+			hits = append(hits, searchHit{repoTodo{Namespace: ns, Repo: n.repo, TodoItem: n.todos[i]}, score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// editDistance returns the Levenshtein distance between a and b, or a value
+// above max as soon as it exceeds max
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// handleSearch answers /api/search?text=... with the best matching TODOs of
+// the accessible namespaces. The q query parameter further filters them with
+// a filter expression, and limit bounds their number.
+func (a *aggregateServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	prefixes := a.readScopes(w, r)
+	if prefixes == nil {
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("at") != "" {
+		http.Error(w, "search covers the latest results only", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, "limit must be a number from 1 to "+strconv.Itoa(maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	q := query.Get("q")
+	if q == "" {
+		writeJSON(w, a.store.index.Search(query.Get("text"), prefixes, limit))
+		return
+	}
+	only, err := parseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Filter the best matches before limiting them
+	now := time.Now()
+	hits := []searchHit{}
+	for _, h := range a.store.index.Search(query.Get("text"), prefixes, maxSearchLimit) {
+		if len(hits) < limit && only(h.TodoItem, now) {
+			hits = append(hits, h)
+		}
+	}
+	writeJSON(w, hits)
+}
+
+// runSearch implements the search command, which finds the tracked TODOs
+// matching words, or close in meaning to a text with --semantic
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	semantic := fs.Bool("semantic", false, "Rank the TODOs by how close their descriptions are in meaning, using the embeddings command of the config file")
+	limit := fs.Int("limit", 20, "Maximum number of TODOs listed")
+	asJSON := fs.Bool("json", false, "Print the matching items as JSON")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) == 0 || *limit < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo search [--tracker=file] [--semantic] [--limit=n] [--json] words...")
+		os.Exit(2)
+	}
+	query := strings.Join(positional, " ")
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+	todos := t.Todos
+
+	var hits []semanticHit
+	if *semantic {
+		if cfg.Embeddings.Command == "" {
+			fmt.Fprintln(os.Stderr, "Error: --semantic needs embeddings.command in the config file")
+			os.Exit(1)
+		}
+		cache, err := loadEmbeddingCache(cfg.Embeddings.Cache)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading embeddings cache: %v\n", err)
+			os.Exit(1)
+		}
+		embedder := commandEmbedder{command: cfg.Embeddings.Command, timeout: time.Minute}
+		if hits, err = semanticSearch(todos, query, embedder, cache, *limit); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		// The cache only saves work, so read-only runs go without it
+		if cfg.Embeddings.Cache != "" && !cfg.ReadOnly {
+			if err := cache.save(cfg.Embeddings.Cache); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving embeddings cache: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	} else {
+		index := newSearchIndex()
+		index.Put(scanResult{Todos: todos})
+		for _, h := range index.Search(query, []string{""}, *limit) {
+			hits = append(hits, semanticHit{TodoItem: h.TodoItem, Score: float64(h.Score)})
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if hits == nil {
+			hits = []semanticHit{}
+		}
+		enc.Encode(hits)
+		return
+	}
+	for _, h := range hits {
+		fmt.Printf("%.2f %s:%d %s %s\n", h.Score, h.File, h.Line, h.Label(), h.Description)
+	}
+}
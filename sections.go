@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section renders one part of a markdown report, or "" when it has nothing
+// to show. ComposeReport lays a report out from a list of sections, so
+// embedders can reorder the built-in ones and add their own without
+// editing the rendered text.
+type Section func(r Report) string
+
+// ComposeReport renders the sections of a report in order
+func ComposeReport(r Report, sections ...Section) string {
+	var b strings.Builder
+	for _, section := range sections {
+		b.WriteString(section(r))
+	}
+	return b.String()
+}
+
+// DefaultSections is the layout of --format=markdown
+func DefaultSections() []Section {
+	return []Section{
+		ExecutiveSection,
+		ListingSection,
+		SuppressedSection,
+		OrphansSection,
+		SkippedSection,
+		ExemptionsSection,
+		OverdueSection,
+		ViolationsSection,
+	}
+}
+
+// markdownOptions returns the rendering options carried by the report
+func (r Report) markdownOptions() markdownOptions {
+	return markdownOptions{Link: r.Link, Owners: r.Owners, IssueLink: r.IssueLink, Now: r.Now, Cluster: r.Cluster}
+}
+
+// SummarySection counts the open, new, stale and resolved items
+func SummarySection(r Report) string {
+	stale := len(staleTodos(r.Todos, r.Now, r.StaleDays))
+	return fmt.Sprintf("_%d open, %d new, %d stale, %d resolved._\n\n", len(r.Todos), len(r.New), stale, len(r.Resolved))
+}
+
+// ListingSection lists the open items grouped the way r.GroupBy asks
+func ListingSection(r Report) string {
+	if r.GroupBy == "assignee" {
+		return ByAssigneeSection(r)
+	}
+	return ByTagSection(r)
+}
+
+// ByTagSection lists the open items with a heading per tag
+func ByTagSection(r Report) string {
+	return formatMarkdown(r.Todos, r.markdownOptions()) + "\n"
+}
+
+// ByAssigneeSection lists the open items with a heading per assignee
+func ByAssigneeSection(r Report) string {
+	return formatMarkdownByAssignee(r.Todos, r.markdownOptions()) + "\n"
+}
+
+// StaleSection lists the open items first seen more than r.StaleDays ago
+func StaleSection(r Report) string {
+	stale := staleTodos(r.Todos, r.Now, r.StaleDays)
+	if len(stale) == 0 {
+		return ""
+	}
+	sortByUrgency(stale)
+	return fmt.Sprintf("# Stale TODOs\n\n_Open for more than %d days._\n\n", r.StaleDays) +
+		formatMarkdownItems(stale, true, r.markdownOptions()) + "\n"
+}
+
+// ResolvedSection lists the items gone since the last run
+func ResolvedSection(r Report) string {
+	if len(r.Resolved) == 0 {
+		return ""
+	}
+	opts := r.markdownOptions()
+	// Resolved lines no longer exist, so they are not linked
+	opts.Link = nil
+	return "# Resolved TODOs\n\n" + formatMarkdownItems(r.Resolved, true, opts) + "\n"
+}
+
+// SkippedSection is the appendix of scan warnings, naming the files that
+// were skipped or not read completely
+func SkippedSection(r Report) string {
+	return formatWarningsMarkdown(r.Warnings)
+}
+
+// ExecutiveSection is the executive summary, when one was written
+func ExecutiveSection(r Report) string {
+	return formatExecutiveMarkdown(r.Executive)
+}
+
+// SuppressedSection notes how many items the suppression rules left out
+func SuppressedSection(r Report) string {
+	return formatSuppressedMarkdown(r.Suppressed)
+}
+
+// OrphansSection counts the items without a ticket, when r.CountOrphans
+func OrphansSection(r Report) string {
+	if !r.CountOrphans {
+		return ""
+	}
+	return formatOrphansMarkdown(r.Todos)
+}
+
+// ExemptionsSection lists the exempted items
+func ExemptionsSection(r Report) string {
+	return formatExemptionsMarkdown(r.Todos, r.Now)
+}
+
+// OverdueSection lists the items past their due date
+func OverdueSection(r Report) string {
+	return formatOverdueMarkdown(r.Todos, r.Now)
+}
+
+// ViolationsSection lists the policy violations
+func ViolationsSection(r Report) string {
+	return formatViolationsMarkdown(r.Violations)
+}
+
+// CustomSection is a section of the embedder's own under a top-level
+// heading; it is left out when render returns ""
+func CustomSection(title string, render func(r Report) string) Section {
+	return func(r Report) string {
+		body := render(r)
+		if body == "" {
+			return ""
+		}
+		return fmt.Sprintf("# %s\n\n%s\n\n", title, strings.TrimRight(body, "\n"))
+	}
+}
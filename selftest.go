@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// goldenItem is the part of a TODO that a fixture fixes in place; dates and
+// tracker state are left out because they change from run to run
+type goldenItem struct {
+	Tag         string `json:"tag"`
+	Description string `json:"description"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+}
+
+func (g goldenItem) String() string {
+	return fmt.Sprintf("%s:%d TODO[%s]: %s", g.File, g.Line, g.Tag, g.Description)
+}
+
+// goldenItems converts scan results to golden items with paths relative to
+// the fixture, sorted by file and line
+func goldenItems(todos []TodoItem, fixture string) []goldenItem {
+	items := make([]goldenItem, 0, len(todos))
+	for _, t := range todos {
+		file := t.File
+		if rel, err := filepath.Rel(fixture, t.File); err == nil {
+			file = rel
+		}
+		items = append(items, goldenItem{Tag: t.Tag, Description: t.Description, File: filepath.ToSlash(file), Line: t.Line})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].File != items[j].File {
+			return items[i].File < items[j].File
+		}
+		return items[i].Line < items[j].Line
+	})
+	return items
+}
+
+// diffGolden returns the expected items that were not found and the found
+// items that were not expected
+func diffGolden(expected, found []goldenItem) ([]goldenItem, []goldenItem) {
+	count := make(map[goldenItem]int)
+	for _, g := range found {
+		count[g]++
+	}
+	var missing []goldenItem
+	for _, g := range expected {
+		if count[g] > 0 {
+			count[g]--
+		} else {
+			missing = append(missing, g)
+		}
+	}
+	var unexpected []goldenItem
+	for _, g := range found {
+		if count[g] > 0 {
+			count[g]--
+			unexpected = append(unexpected, g)
+		}
+	}
+	return missing, unexpected
+}
+
+// runSelftest scans a fixture tree with the current configuration and
+// compares the result with a golden file
+func runSelftest(args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	fixture := fs.String("fixture", "", "Directory to scan")
+	golden := fs.String("golden", "", "JSON file with the expected TODOs")
+	update := fs.Bool("update", false, "Write the scan result to the golden file instead of comparing")
+	fs.Parse(args)
+	if *fixture == "" || *golden == "" {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo selftest --fixture=dir --golden=expected.json [--update]")
+		os.Exit(2)
+	}
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	scanner, err := NewScannerFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	result, err := scanner.Scan(cfg.ScanOptions(*fixture))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning todos: %v\n", err)
+		os.Exit(1)
+	}
+	found := goldenItems(result.Todos, *fixture)
+
+	if *update {
+		if err := refuseWrite(cfg, *golden); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(found, "", "  ")
+		if err := os.WriteFile(*golden, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing golden file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %d items to %s\n", len(found), *golden)
+		return
+	}
+
+	data, err := os.ReadFile(*golden)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading golden file: %v\n", err)
+		os.Exit(1)
+	}
+	var expected []goldenItem
+	if err := json.Unmarshal(data, &expected); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing golden file %s: %v\n", *golden, err)
+		os.Exit(1)
+	}
+
+	missing, unexpected := diffGolden(expected, found)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		fmt.Printf("PASS: %d items match %s\n", len(found), *golden)
+		return
+	}
+	for _, g := range missing {
+		fmt.Printf("- %s\n", g)
+	}
+	for _, g := range unexpected {
+		fmt.Printf("+ %s\n", g)
+	}
+	fmt.Printf("FAIL: %d missing, %d unexpected\n", len(missing), len(unexpected))
+	os.Exit(1)
+}
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIngestSize bounds the body of a pushed scan result
+const maxIngestSize = 32 << 20
+
+// scanResult is the scan of one repository, as pushed to the aggregation
+// server
+type scanResult struct {
+	// Namespace is org/repo/branch, set by the server
+	Namespace string     `json:"namespace,omitempty"`
+	Repo      string     `json:"repo"`
+	Branch    string     `json:"branch,omitempty"`
+	Commit    string     `json:"commit,omitempty"`
+	Received  string     `json:"received,omitempty"`
+	Todos     []TodoItem `json:"todos"`
+}
+
+// namespaceOf returns the org/repo/branch namespace of a result
+func namespaceOf(r scanResult) string {
+	if r.Branch == "" {
+		return r.Repo
+	}
+	return r.Repo + "/" + r.Branch
+}
+
+// checkNamespace rejects namespaces with empty, "." or ".." segments, which
+// would name a directory outside the snapshots once used as a path
+func checkNamespace(namespace string) error {
+	for _, segment := range strings.Split(namespace, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("namespace %q has an empty, . or .. segment", namespace)
+		}
+	}
+	return nil
+}
+
+// inNamespace reports whether namespace is prefix or lies below it
+func inNamespace(namespace, prefix string) bool {
+	return prefix == "" || namespace == prefix || strings.HasPrefix(namespace, prefix+"/")
+}
+
+// resultStore keeps the latest scan result of every namespace, one JSON file
+// per namespace in a directory
+type resultStore struct {
+	dir     string
+	mu      sync.RWMutex
+	results map[string]scanResult
+	// index is the full-text index of the results
+	index *searchIndex
+}
+
+func openResultStore(dir string) (*resultStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &resultStore{dir: dir, results: make(map[string]scanResult), index: newSearchIndex()}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var r scanResult
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if r.Namespace == "" {
+			r.Namespace = namespaceOf(r)
+		}
+		s.results[r.Namespace] = r
+		s.index.Put(r)
+	}
+	return s, nil
+}
+
+func (s *resultStore) path(namespace string) string {
+	return filepath.Join(s.dir, url.PathEscape(namespace)+".json")
+}
+
+// Put stores a result, replacing the previous one of the same namespace
+func (s *resultStore) Put(r scanResult) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.WriteFile(s.path(r.Namespace), data, 0o644); err != nil {
+		return err
+	}
+	s.results[r.Namespace] = r
+	s.index.Put(r)
+	return nil
+}
+
+// List returns the stored results in any of the given namespaces, sorted by
+// namespace
+func (s *resultStore) List(prefixes ...string) []scanResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]scanResult, 0, len(s.results))
+	for ns, r := range s.results {
+		for _, prefix := range prefixes {
+			if inNamespace(ns, prefix) {
+				list = append(list, r)
+				break
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Namespace < list[j].Namespace })
+	return list
+}
+
+// Prune removes the results received before the cutoff returned for their
+// namespace; a zero cutoff keeps them forever
+func (s *resultStore) Prune(cutoff func(namespace string) time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ns, r := range s.results {
+		limit := cutoff(ns)
+		received, err := time.Parse(time.RFC3339, r.Received)
+		if limit.IsZero() || err != nil || !received.Before(limit) {
+			continue
+		}
+		if err := os.Remove(s.path(ns)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(s.results, ns)
+		s.index.Remove(ns)
+	}
+	return nil
+}
+
+// tenant gives a team its own API token and retention for the namespaces
+// below a prefix such as acme or acme/api
+type tenant struct {
+	Namespace string `json:"namespace"`
+	Token     string `json:"token"`
+	Retention string `json:"retention,omitempty"`
+}
+
+// loadTenants reads the tenants file: {"tenants": [...]}
+func loadTenants(path string) ([]tenant, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var file struct {
+		Tenants []tenant `json:"tenants"`
+	}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, t := range file.Tenants {
+		if t.Namespace == "" || t.Token == "" {
+			return nil, fmt.Errorf("%s: every tenant needs a namespace and a token", path)
+		}
+		if t.Retention != "" {
+			if _, err := parseDays(t.Retention); err != nil {
+				return nil, fmt.Errorf("%s: tenant %s: %v", path, t.Namespace, err)
+			}
+		}
+	}
+	return file.Tenants, nil
+}
+
+// repoTodo is a TODO with the namespace it was found in
+type repoTodo struct {
+	Namespace string `json:"namespace"`
+	Repo      string `json:"repo"`
+	TodoItem
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<html>
+<head><title>TODO Dashboard</title></head>
+<body style="font-family: sans-serif">
+<h1>TODO Dashboard</h1>
+<p>{{.Total}} open TODOs in {{len .Repos}} namespaces</p>
+<input id="search" type="search" placeholder="Search descriptions and paths" size="50" autocomplete="off">
+<ul id="hits"></ul>
+<script>
+// Search as you type, dropping answers to outdated queries
+var search = document.getElementById("search"), hits = document.getElementById("hits"), latest = 0;
+search.addEventListener("input", function () {
+  var n = ++latest;
+  if (search.value.trim() === "") { hits.replaceChildren(); return; }
+  fetch("api/search?limit=20&text=" + encodeURIComponent(search.value)).then(function (r) { return r.json(); }).then(function (found) {
+    if (n !== latest) return;
+    hits.replaceChildren.apply(hits, found.map(function (h) {
+      var li = document.createElement("li");
+      li.textContent = h.namespace + " " + h.file + ":" + h.line + " [" + h.tag + "] " + h.description;
+      return li;
+    }));
+  });
+});
+</script>
+<h2>Namespaces</h2>
+<table>
+<tr><th>Namespace</th><th>Branch</th><th>Commit</th><th>Open</th><th>Received</th></tr>
+{{range .Repos}}<tr><td><a href="api/todos?namespace={{.Namespace}}">{{.Namespace}}</a></td><td>{{.Branch}}</td><td><code>{{.Commit}}</code></td><td>{{len .Todos}}</td><td>{{.Received}}</td></tr>
+{{end}}</table>
+<h2>Tags</h2>
+<table>
+<tr><th>Tag</th><th>Open</th></tr>
+{{range .Tags}}<tr><td><a href="api/todos?tag={{.Tag}}">{{.Tag}}</a></td><td>{{.Count}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// aggregateServer serves the pushed results as dashboards and JSON APIs.
+// The admin token gives access to every namespace; tenant tokens only to
+// their own.
+type aggregateServer struct {
+	store     *resultStore
+	token     string
+	tenants   []tenant
+	retention int
+	// cache is nil unless Redis is configured
+	cache *redisCache
+	// snapshots is nil unless history is kept
+	snapshots         *snapshotStore
+	snapshotRetention snapshotRetention
+}
+
+func (a *aggregateServer) routes() *http.ServeMux {
+	handle := func(h http.HandlerFunc) http.HandlerFunc {
+		if a.cache == nil {
+			return h
+		}
+		// Responses depend on the namespaces the token may read
+		return a.cache.wrap(a.client, func(r *http.Request) string {
+			return strings.Join(a.scopes(r, false), ",")
+		}, h)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handle(a.handleDashboard))
+	mux.HandleFunc("/api/ingest", handle(a.handleIngest))
+	mux.HandleFunc("/api/repos", handle(a.handleRepos))
+	mux.HandleFunc("/api/todos", handle(a.handleTodos))
+	mux.HandleFunc("/api/tags", handle(a.handleTags))
+	mux.HandleFunc("/api/search", handle(a.handleSearch))
+	return mux
+}
+
+// requestToken returns the bearer token of a request, or the password of its
+// basic authentication so that browsers can open the dashboard
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	_, password, _ := r.BasicAuth()
+	return password
+}
+
+// client identifies who sends a request, for rate limiting: the admin or the
+// tenant its token authenticates, or else its address, so that sending a new
+// made-up token with every request does not escape the limit
+func (a *aggregateServer) client(r *http.Request) string {
+	token := requestToken(r)
+	equal := func(s string) bool { return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s)) == 1 }
+	if equal(a.token) {
+		return "admin"
+	}
+	for _, t := range a.tenants {
+		if equal(t.Token) {
+			return "tenant:" + t.Namespace
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// scopes returns the namespaces a request may access. Without tenants,
+// reading is open to everyone and writing needs the admin token.
+func (a *aggregateServer) scopes(r *http.Request, write bool) []string {
+	token := requestToken(r)
+	equal := func(s string) bool { return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s)) == 1 }
+	if equal(a.token) || (!write && len(a.tenants) == 0) {
+		return []string{""}
+	}
+	var prefixes []string
+	for _, t := range a.tenants {
+		if equal(t.Token) {
+			prefixes = append(prefixes, t.Namespace)
+		}
+	}
+	return prefixes
+}
+
+// readScopes returns the namespaces a read request may access, narrowed to
+// the namespace query parameter, or answers 401 and returns nil
+func (a *aggregateServer) readScopes(w http.ResponseWriter, r *http.Request) []string {
+	prefixes := a.scopes(r, false)
+	if len(prefixes) == 0 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="collecttodo"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		return prefixes
+	}
+	var narrowed []string
+	for _, prefix := range prefixes {
+		if inNamespace(ns, prefix) {
+			narrowed = append(narrowed, ns)
+		} else if inNamespace(prefix, ns) {
+			narrowed = append(narrowed, prefix)
+		}
+	}
+	if narrowed == nil {
+		// Nothing is visible, but the request was authorized
+		narrowed = []string{}
+	}
+	return narrowed
+}
+
+// retentionCutoff returns the time before which results of a namespace are
+// deleted: the retention of the most specific tenant, or the default
+func (a *aggregateServer) retentionCutoff(namespace string, now time.Time) time.Time {
+	days, depth := a.retention, -1
+	for _, t := range a.tenants {
+		if t.Retention != "" && inNamespace(namespace, t.Namespace) && len(t.Namespace) > depth {
+			days, _ = parseDays(t.Retention)
+			depth = len(t.Namespace)
+		}
+	}
+	if days == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -days)
+}
+
+// results returns the results visible in the namespaces of the prefixes: the
+// latest ones or, with the at query parameter, the snapshots taken on or
+// before that day. The q query parameter filters their TODOs with a filter
+// expression. It answers 400 and returns false when a parameter is invalid.
+func (a *aggregateServer) results(w http.ResponseWriter, r *http.Request, prefixes []string) ([]scanResult, bool) {
+	results, ok := a.snapshotResults(w, r, prefixes)
+	q := r.URL.Query().Get("q")
+	if !ok || q == "" {
+		return results, ok
+	}
+	f, err := parseFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	now := time.Now()
+	for i := range results {
+		results[i].Todos = applyFilter(results[i].Todos, f, now)
+	}
+	return results, true
+}
+
+func (a *aggregateServer) snapshotResults(w http.ResponseWriter, r *http.Request, prefixes []string) ([]scanResult, bool) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		return a.store.List(prefixes...), true
+	}
+	if _, err := time.Parse("2006-01-02", at); err != nil || a.snapshots == nil {
+		http.Error(w, "at must be a date such as 2025-06-30, and snapshots must be enabled", http.StatusBadRequest)
+		return nil, false
+	}
+	namespaces, err := a.snapshots.Namespaces()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+		http.Error(w, "reading snapshots failed", http.StatusInternalServerError)
+		return nil, false
+	}
+	results := []scanResult{}
+	for _, ns := range namespaces {
+		visible := false
+		for _, prefix := range prefixes {
+			visible = visible || inNamespace(ns, prefix)
+		}
+		if !visible {
+			continue
+		}
+		res, found, err := a.snapshots.At(ns, at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+			continue
+		}
+		if found {
+			results = append(results, res)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Namespace < results[j].Namespace })
+	return results, true
+}
+
+func (a *aggregateServer) prune() {
+	now := time.Now()
+	err := a.store.Prune(func(ns string) time.Time { return a.retentionCutoff(ns, now) })
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error pruning results: %v\n", err)
+	}
+	if a.snapshots != nil {
+		namespaces, err := a.snapshots.Namespaces()
+		for _, ns := range namespaces {
+			if err == nil {
+				err = a.snapshots.Prune(ns, a.snapshotRetention, now)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pruning snapshots: %v\n", err)
+		}
+	}
+	if a.cache != nil {
+		a.cache.invalidate()
+	}
+}
+
+func (a *aggregateServer) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	prefixes := a.scopes(r, true)
+	if len(prefixes) == 0 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var result scanResult
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestSize))
+	if err := dec.Decode(&result); err != nil {
+		http.Error(w, fmt.Sprintf("invalid scan result: %v", err), http.StatusBadRequest)
+		return
+	}
+	if result.Repo == "" {
+		http.Error(w, "invalid scan result: repo is required", http.StatusBadRequest)
+		return
+	}
+	result.Namespace = namespaceOf(result)
+	if err := checkNamespace(result.Namespace); err != nil {
+		http.Error(w, fmt.Sprintf("invalid scan result: %v", err), http.StatusBadRequest)
+		return
+	}
+	allowed := false
+	for _, prefix := range prefixes {
+		allowed = allowed || inNamespace(result.Namespace, prefix)
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("token may not push to %s", result.Namespace), http.StatusForbidden)
+		return
+	}
+	result.Received = time.Now().UTC().Format(time.RFC3339)
+	if err := a.store.Put(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error storing result of %s: %v\n", result.Repo, err)
+		http.Error(w, "storing result failed", http.StatusInternalServerError)
+		return
+	}
+	if a.snapshots != nil {
+		if err := a.snapshots.Save(result.Namespace, result.Received[:len("2006-01-02")], result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving snapshot of %s: %v\n", result.Namespace, err)
+		}
+	}
+	if a.cache != nil {
+		a.cache.invalidate()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *aggregateServer) handleRepos(w http.ResponseWriter, r *http.Request) {
+	prefixes := a.readScopes(w, r)
+	if prefixes == nil {
+		return
+	}
+	type repoSummary struct {
+		Namespace string `json:"namespace"`
+		Repo      string `json:"repo"`
+		Branch    string `json:"branch,omitempty"`
+		Commit    string `json:"commit,omitempty"`
+		Received  string `json:"received"`
+		Open      int    `json:"open"`
+	}
+	repos := []repoSummary{}
+	results, ok := a.results(w, r, prefixes)
+	if !ok {
+		return
+	}
+	for _, res := range results {
+		repos = append(repos, repoSummary{Namespace: res.Namespace, Repo: res.Repo, Branch: res.Branch, Commit: res.Commit, Received: res.Received, Open: len(res.Todos)})
+	}
+	writeJSON(w, repos)
+}
+
+// handleTodos lists the TODOs of every accessible namespace, optionally
+// filtered with the namespace, repo and tag query parameters
+func (a *aggregateServer) handleTodos(w http.ResponseWriter, r *http.Request) {
+	prefixes := a.readScopes(w, r)
+	if prefixes == nil {
+		return
+	}
+	repo, tag := r.URL.Query().Get("repo"), r.URL.Query().Get("tag")
+	todos := []repoTodo{}
+	results, ok := a.results(w, r, prefixes)
+	if !ok {
+		return
+	}
+	for _, res := range results {
+		if repo != "" && res.Repo != repo {
+			continue
+		}
+		for _, t := range res.Todos {
+			if tag == "" || t.Tag == tag {
+				todos = append(todos, repoTodo{Namespace: res.Namespace, Repo: res.Repo, TodoItem: t})
+			}
+		}
+	}
+	writeJSON(w, todos)
+}
+
+func (a *aggregateServer) handleTags(w http.ResponseWriter, r *http.Request) {
+	prefixes := a.readScopes(w, r)
+	if prefixes == nil {
+		return
+	}
+	results, ok := a.results(w, r, prefixes)
+	if !ok {
+		return
+	}
+	writeJSON(w, countTags(results))
+}
+
+func (a *aggregateServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	prefixes := a.readScopes(w, r)
+	if prefixes == nil {
+		return
+	}
+	results, ok := a.results(w, r, prefixes)
+	if !ok {
+		return
+	}
+	total := 0
+	for _, res := range results {
+		total += len(res.Todos)
+	}
+	data := struct {
+		Total int
+		Repos []scanResult
+		Tags  []tagCount
+	}{total, results, countTags(results)}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering dashboard: %v\n", err)
+	}
+}
+
+// countTags totals the open TODOs of every tag across results, most used first
+func countTags(results []scanResult) []tagCount {
+	counts := make(map[string]int)
+	for _, res := range results {
+		for _, t := range res.Todos {
+			counts[t.Tag]++
+		}
+	}
+	tags := []tagCount{}
+	for tag, n := range counts {
+		tags = append(tags, tagCount{Tag: tag, Count: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing response: %v\n", err)
+	}
+}
+
+// runAggregate implements the aggregate command, a central registry that
+// collects the scan results pushed by many repositories
+func runAggregate(args []string) {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "Address to serve on")
+	dataDir := fs.String("data", "aggregate-data", "Directory where pushed results are stored")
+	token := fs.String("token", os.Getenv(envPrefix+"AGGREGATE_TOKEN"), "Admin bearer token, valid for every namespace (default $COLLECTTODO_AGGREGATE_TOKEN)")
+	tenantsPath := fs.String("tenants", "", "JSON file giving namespaces their own tokens and retention")
+	retention := fs.String("retention", "", "Age such as 90d after which results are deleted, unless a tenant sets its own (default: keep forever)")
+	redisURL := fs.String("redis", os.Getenv(envPrefix+"REDIS_URL"), "Redis URL, redis://[:password@]host:port/db, for caching and rate limiting (default $COLLECTTODO_REDIS_URL)")
+	snapshots := fs.String("snapshots", "", "Directory where dated snapshots of every namespace are kept for point-in-time queries")
+	keepDaily := fs.String("keep-daily", "", "Age such as 90d until which every daily snapshot is kept; older ones are thinned to one per week (default: keep all)")
+	keepWeekly := fs.String("keep-weekly", "", "Age such as 104w after which snapshots are deleted (default: keep weekly snapshots forever)")
+	cacheTTL := fs.Duration("cache-ttl", time.Minute, "How long query results stay cached in Redis, rounded up to whole seconds")
+	rateLimit := fs.Int("rate-limit", 600, "Requests per minute allowed to the admin, each tenant, or each client address without a valid token when Redis is used; 0 disables the limit")
+	fs.Parse(args)
+
+	if *token == "" {
+		fmt.Fprintf(os.Stderr, "Error: --token or %sAGGREGATE_TOKEN is required\n", envPrefix)
+		os.Exit(1)
+	}
+	store, err := openResultStore(*dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", *dataDir, err)
+		os.Exit(1)
+	}
+	server := &aggregateServer{store: store, token: *token}
+	if *tenantsPath != "" {
+		if server.tenants, err = loadTenants(*tenantsPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading tenants: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *retention != "" {
+		if server.retention, err = parseDays(*retention); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --retention: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *redisURL != "" {
+		client, err := newRedisClient(*redisURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --redis: %v\n", err)
+			os.Exit(1)
+		}
+		server.cache = &redisCache{client: client, ttl: *cacheTTL, rateLimit: *rateLimit}
+	}
+	if *snapshots != "" {
+		server.snapshots = &snapshotStore{dir: *snapshots}
+		if server.snapshotRetention, err = parseSnapshotRetention(*keepDaily, *keepWeekly); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	server.prune()
+	go func() {
+		for range time.Tick(time.Hour) {
+			server.prune()
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Serving TODO dashboard on %s\n", *listen)
+	if err := http.ListenAndServe(*listen, server.routes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+}
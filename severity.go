@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities, from the least to the most severe
+const (
+	severityInfo    = "info"
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+// severityRanks orders severities: error is 0, warning 1 and info 2
+var severityRanks = map[string]int{severityError: 0, severityWarning: 1, severityInfo: 2}
+
+// defaultSeverities maps keywords to their severity; keywords missing from
+// it and from the severities setting are warnings
+var defaultSeverities = map[string]string{
+	"NOTE":  severityInfo,
+	"TODO":  severityWarning,
+	"FIXME": severityError,
+	"HACK":  severityError,
+	"XXX":   severityError,
+	"BUG":   severityError,
+}
+
+// severityRank returns the rank of a severity; items without one rank as
+// warnings
+func severityRank(severity string) int {
+	if rank, ok := severityRanks[severity]; ok {
+		return rank
+	}
+	return severityRanks[severityWarning]
+}
+
+// severityMap merges the configured keyword severities over the defaults,
+// with the keywords in upper case
+func severityMap(configured map[string]string) map[string]string {
+	m := make(map[string]string, len(defaultSeverities)+len(configured))
+	for k, v := range defaultSeverities {
+		m[k] = v
+	}
+	for k, v := range configured {
+		m[strings.ToUpper(k)] = v
+	}
+	return m
+}
+
+// severityOf returns the severity of a keyword
+func severityOf(severities map[string]string, keyword string) string {
+	if keyword == "" {
+		keyword = defaultKeyword
+	}
+	if s, ok := severities[strings.ToUpper(keyword)]; ok {
+		return s
+	}
+	return severityWarning
+}
+
+// checkSeverity reports every item at least as severe as min
+func checkSeverity(todos []TodoItem, min string) []policyViolation {
+	var violations []policyViolation
+	for _, t := range todos {
+		if severityRank(t.Severity) <= severityRank(min) {
+			violations = append(violations, policyViolation{
+				policy:  "fail-on-severity",
+				message: fmt.Sprintf("%s %s (%s:%d) has severity %s", t.Label(), t.Description, t.File, t.Line, t.Severity),
+			})
+		}
+	}
+	return violations
+}
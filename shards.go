@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A tracker path that is a directory, or ends with a slash, holds a sharded
+// tracker: one file per top-level directory of the repository, such as
+// .collecttodo/tracker/services.json, so that a change to one directory only
+// rewrites its shard
+
+// rootShard is the shard of the files at the top of the repository
+const rootShard = "_root"
+
+// shardedTracker reports whether a tracker path is a directory of shards
+func shardedTracker(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// trackerShard returns the shard an item of a file is kept in
+func trackerShard(file string) string {
+	p := repoPath(file)
+	if i := strings.Index(p, "/"); i > 0 {
+		return p[:i]
+	}
+	return rootShard
+}
+
+// shardFiles lists the shard files of a directory, by shard name
+func shardFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		files[strings.TrimSuffix(name, ".json")] = filepath.Join(dir, name)
+	}
+	return files, nil
+}
+
+// loadShards reads every shard of a sharded tracker into one tracker. Unless
+// strict, shards that cannot be decoded are left out, like a corrupt tracker
+// file.
+func loadShards(dir string, strict bool) (TodoTracker, error) {
+	var tracker TodoTracker
+	files, err := shardFiles(dir)
+	if err != nil {
+		return tracker, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := os.Open(files[name])
+		if err != nil {
+			return TodoTracker{}, err
+		}
+		shard, err := decodeTracker(f)
+		f.Close()
+		if err == errZstd {
+			return TodoTracker{}, err
+		}
+		if err != nil {
+			if strict {
+				return TodoTracker{}, fmt.Errorf("decoding %s: %w", files[name], err)
+			}
+			continue
+		}
+		tracker.Todos = append(tracker.Todos, shard.Todos...)
+		tracker.Suppressed = append(tracker.Suppressed, shard.Suppressed...)
+	}
+	return tracker, nil
+}
+
+// saveShards writes the items of a tracker to their shards. Shards whose
+// content is unchanged are not rewritten, and those left without items are
+// removed.
+func saveShards(dir string, tracker TodoTracker) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	shards := make(map[string]*TodoTracker)
+	shard := func(file string) *TodoTracker {
+		name := trackerShard(file)
+		if shards[name] == nil {
+			shards[name] = &TodoTracker{Todos: []TodoItem{}}
+		}
+		return shards[name]
+	}
+	for _, t := range tracker.Todos {
+		s := shard(t.File)
+		s.Todos = append(s.Todos, t)
+	}
+	for _, t := range tracker.Suppressed {
+		s := shard(t.File)
+		s.Suppressed = append(s.Suppressed, t)
+	}
+	existing, err := shardFiles(dir)
+	if err != nil {
+		return err
+	}
+	for name, s := range shards {
+		// Scans of part of the tree append the items they kept, so the order
+		// is restored for stable diffs
+		sort.SliceStable(s.Todos, func(i, j int) bool {
+			a, b := repoPath(s.Todos[i].File), repoPath(s.Todos[j].File)
+			if a != b {
+				return a < b
+			}
+			return s.Todos[i].Line < s.Todos[j].Line
+		})
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		path := filepath.Join(dir, name+".json")
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	for name, path := range existing {
+		if shards[name] == nil {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// partitionByRoots splits items into those under one of the scanned roots
+// and the others, which a scan of a sharded tracker leaves as they are
+func partitionByRoots(todos []TodoItem, roots []string) (scanned, kept []TodoItem) {
+	for _, t := range todos {
+		if underRoots(t.File, roots) {
+			scanned = append(scanned, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	return scanned, kept
+}
+
+func underRoots(file string, roots []string) bool {
+	file = pathKey(file)
+	for _, root := range roots {
+		r := pathKey(root)
+		if r == "." || file == r || strings.HasPrefix(file, r+"/") {
+			return true
+		}
+	}
+	return false
+}
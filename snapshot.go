@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotStore keeps dated snapshots of the full item set, one file per day
+// in a directory per namespace, so that past states can be queried. The
+// daemon uses the empty namespace.
+type snapshotStore struct {
+	dir string
+}
+
+// snapshotRetention thins out old snapshots: every daily snapshot is kept for
+// daily days, then one per week until weekly days; older ones are deleted.
+// Zero keeps snapshots forever.
+type snapshotRetention struct {
+	daily  int
+	weekly int
+}
+
+// parseSnapshotRetention parses the --keep-daily and --keep-weekly ages
+func parseSnapshotRetention(daily, weekly string) (snapshotRetention, error) {
+	var r snapshotRetention
+	var err error
+	if daily != "" {
+		if r.daily, err = parseDays(daily); err != nil {
+			return r, fmt.Errorf("--keep-daily: %v", err)
+		}
+	}
+	if weekly != "" {
+		if r.weekly, err = parseDays(weekly); err != nil {
+			return r, fmt.Errorf("--keep-weekly: %v", err)
+		}
+	}
+	return r, nil
+}
+
+func (s snapshotStore) namespaceDir(namespace string) string {
+	if namespace == "" {
+		return s.dir
+	}
+	return filepath.Join(s.dir, url.PathEscape(namespace))
+}
+
+// Save stores the snapshot of a namespace for a day, replacing an earlier
+// one of the same day
+func (s snapshotStore) Save(namespace, date string, r scanResult) error {
+	dir := s.namespaceDir(namespace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, date+".json"), data, 0o644)
+}
+
+// Dates returns the days a namespace has snapshots for, oldest first
+func (s snapshotStore) Dates(namespace string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.namespaceDir(namespace), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var dates []string
+	for _, file := range files {
+		date := strings.TrimSuffix(filepath.Base(file), ".json")
+		if _, err := time.Parse("2006-01-02", date); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// At returns the latest snapshot of a namespace taken on or before a day
+func (s snapshotStore) At(namespace, date string) (scanResult, bool, error) {
+	var r scanResult
+	dates, err := s.Dates(namespace)
+	if err != nil {
+		return r, false, err
+	}
+	i := sort.SearchStrings(dates, date)
+	if i < len(dates) && dates[i] == date {
+		i++
+	}
+	if i == 0 {
+		return r, false, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.namespaceDir(namespace), dates[i-1]+".json"))
+	if err != nil {
+		return r, false, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, false, fmt.Errorf("snapshot %s of %q: %w", dates[i-1], namespace, err)
+	}
+	return r, true, nil
+}
+
+// Namespaces returns the namespaces that have snapshots
+func (s snapshotStore) Namespaces() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if ns, err := url.PathUnescape(e.Name()); err == nil {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces, nil
+}
+
+// Prune applies the retention to the snapshots of a namespace
+func (s snapshotStore) Prune(namespace string, r snapshotRetention, now time.Time) error {
+	if r.daily == 0 {
+		return nil
+	}
+	dates, err := s.Dates(namespace)
+	if err != nil {
+		return err
+	}
+	dailyAfter := now.AddDate(0, 0, -r.daily).Format("2006-01-02")
+	weeklyAfter := ""
+	if r.weekly > 0 {
+		weeklyAfter = now.AddDate(0, 0, -r.weekly).Format("2006-01-02")
+	}
+	keptWeeks := make(map[string]bool)
+	for _, date := range dates {
+		if date >= dailyAfter {
+			continue
+		}
+		day, _ := time.Parse("2006-01-02", date)
+		year, week := day.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%d", year, week)
+		if date >= weeklyAfter && !keptWeeks[weekKey] {
+			// Keep the first snapshot of every week
+			keptWeeks[weekKey] = true
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.namespaceDir(namespace), date+".json")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHistory implements the history command, which lists the snapshots kept
+// by the daemon or prints the summary of the tree as it was on a day
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dir := fs.String("snapshots", "snapshots", "Directory of the daemon's snapshots")
+	at := fs.String("at", "", "Print the TODOs open on this day (YYYY-MM-DD) instead of listing the snapshots")
+	fs.Parse(args)
+
+	store := snapshotStore{dir: *dir}
+	if *at == "" {
+		dates, err := store.Dates("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		for _, date := range dates {
+			snap, _, err := store.At("", date)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s  %d open\n", date, len(snap.Todos))
+		}
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", *at); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --at must be a date such as 2025-06-30\n")
+		os.Exit(1)
+	}
+	snap, ok, err := store.At("", *at)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+		os.Exit(1)
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: no snapshot on or before %s\n", *at)
+		os.Exit(1)
+	}
+	fmt.Print(formatMarkdown(snap.Todos, markdownOptions{}))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// SonarQube generic issue import types
+
+type sonarReport struct {
+	Issues []sonarIssue `json:"issues"`
+}
+
+type sonarIssue struct {
+	EngineID        string        `json:"engineId"`
+	RuleID          string        `json:"ruleId"`
+	Severity        string        `json:"severity"`
+	Type            string        `json:"type"`
+	PrimaryLocation sonarLocation `json:"primaryLocation"`
+	// EffortMinutes is the remediation effort, from a duration estimate
+	// such as effort:2h
+	EffortMinutes int `json:"effortMinutes,omitempty"`
+}
+
+type sonarLocation struct {
+	Message   string `json:"message"`
+	FilePath  string `json:"filePath"`
+	TextRange struct {
+		StartLine int `json:"startLine"`
+		// StartColumn counts characters from 0
+		StartColumn *int `json:"startColumn,omitempty"`
+	} `json:"textRange"`
+}
+
+// sonarSeverities maps severities to SonarQube's
+var sonarSeverities = map[string]string{"info": "INFO", "warning": "MINOR", "error": "MAJOR"}
+
+// formatSonarReport renders the TODOs as SonarQube generic issues, code
+// smells with the tag as rule, for --format=sonar
+func formatSonarReport(r Report) (string, error) {
+	report := sonarReport{Issues: []sonarIssue{}}
+	for _, t := range r.Todos {
+		rule := t.Tag
+		if rule == "" {
+			rule = "untagged"
+		}
+		severity := sonarSeverities[t.Severity]
+		if severity == "" {
+			severity = "MINOR"
+		}
+		issue := sonarIssue{
+			EngineID: "collecttodo",
+			RuleID:   rule,
+			Severity: severity,
+			Type:     "CODE_SMELL",
+		}
+		issue.PrimaryLocation.Message = t.Label() + ": " + t.Description
+		issue.PrimaryLocation.FilePath = repoPath(t.File)
+		issue.PrimaryLocation.TextRange.StartLine = t.Line
+		if t.RuneColumn > 0 {
+			column := t.RuneColumn - 1
+			issue.PrimaryLocation.TextRange.StartColumn = &column
+		}
+		if d, err := time.ParseDuration(strings.ToLower(effortOf(t))); err == nil && d > 0 {
+			issue.EffortMinutes = int(d.Minutes())
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultAgeBuckets are the boundaries of the age distribution in stats
+const defaultAgeBuckets = "1w,1m,6m"
+
+// ageBuckets splits ages in days at ascending boundaries
+type ageBuckets struct {
+	bounds []int
+	labels []string
+}
+
+// parseAgeBuckets parses comma-separated boundaries such as 1w,1m,6m into
+// the buckets <1w, 1w–1m, 1m–6m and >6m
+func parseAgeBuckets(spec string) (ageBuckets, error) {
+	var b ageBuckets
+	names := splitList(spec)
+	for i, name := range names {
+		days, err := parseDays(name)
+		if err != nil {
+			return b, err
+		}
+		if i > 0 && days <= b.bounds[i-1] {
+			return b, fmt.Errorf("age buckets must be ascending, got %s after %s", name, names[i-1])
+		}
+		b.bounds = append(b.bounds, days)
+	}
+	if len(names) == 0 {
+		return b, fmt.Errorf("no age buckets given")
+	}
+	b.labels = append(b.labels, "<"+names[0])
+	for i := 1; i < len(names); i++ {
+		b.labels = append(b.labels, names[i-1]+"–"+names[i])
+	}
+	b.labels = append(b.labels, ">"+names[len(names)-1])
+	return b, nil
+}
+
+// index returns the bucket of an age
+func (b ageBuckets) index(days int) int {
+	for i, bound := range b.bounds {
+		if days < bound {
+			return i
+		}
+	}
+	return len(b.bounds)
+}
+
+// tagStats is the number of open TODOs of a tag, in total and per age bucket
+type tagStats struct {
+	Tag string `json:"tag"`
+	// Owner is the team owning the tag in the configuration, if any
+	Owner   string `json:"owner,omitempty"`
+	Total   int    `json:"total"`
+	Buckets []int  `json:"buckets"`
+}
+
+// stats summarizes the open TODOs per tag
+type stats struct {
+	Buckets []string   `json:"buckets"`
+	Tags    []tagStats `json:"tags"`
+	Total   tagStats   `json:"total"`
+	// Velocity is only computed when snapshots are given
+	Velocity []weekVelocity `json:"velocity,omitempty"`
+}
+
+func computeStats(todos []TodoItem, buckets ageBuckets, now time.Time) stats {
+	s := stats{Buckets: buckets.labels, Total: tagStats{Tag: "total", Buckets: make([]int, len(buckets.labels))}}
+	byTag := make(map[string]*tagStats)
+	for _, t := range todos {
+		ts, ok := byTag[t.Tag]
+		if !ok {
+			ts = &tagStats{Tag: t.Tag, Buckets: make([]int, len(buckets.labels))}
+			byTag[t.Tag] = ts
+		}
+		i := buckets.index(ageInDays(t, now))
+		ts.Total++
+		ts.Buckets[i]++
+		s.Total.Total++
+		s.Total.Buckets[i]++
+	}
+	for _, ts := range byTag {
+		s.Tags = append(s.Tags, *ts)
+	}
+	sort.Slice(s.Tags, func(i, j int) bool { return s.Tags[i].Tag < s.Tags[j].Tag })
+	return s
+}
+
+func formatStatsMarkdown(s stats) string {
+	var b strings.Builder
+	b.WriteString("# TODO Statistics\n\n")
+	owned := false
+	for _, ts := range s.Tags {
+		owned = owned || ts.Owner != ""
+	}
+	if owned {
+		b.WriteString("| Tag | Owner | Total | " + strings.Join(s.Buckets, " | ") + " |\n")
+		b.WriteString("| --- | --- | ---: |" + strings.Repeat(" ---: |", len(s.Buckets)) + "\n")
+	} else {
+		b.WriteString("| Tag | Total | " + strings.Join(s.Buckets, " | ") + " |\n")
+		b.WriteString("| --- | ---: |" + strings.Repeat(" ---: |", len(s.Buckets)) + "\n")
+	}
+	row := func(ts tagStats, bold bool) {
+		cells := []string{ts.Tag, fmt.Sprint(ts.Total)}
+		if owned {
+			cells = []string{ts.Tag, ts.Owner, fmt.Sprint(ts.Total)}
+		}
+		for _, n := range ts.Buckets {
+			cells = append(cells, fmt.Sprint(n))
+		}
+		if bold {
+			for i := range cells {
+				if cells[i] != "" {
+					cells[i] = "**" + cells[i] + "**"
+				}
+			}
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	for _, ts := range s.Tags {
+		row(ts, false)
+	}
+	row(s.Total, true)
+	if len(s.Velocity) > 0 {
+		b.WriteString("\n## Velocity\n\n" + formatVelocityMarkdown(s.Velocity))
+	}
+	return b.String()
+}
+
+// runStats implements the stats command, which shows how many TODOs of each
+// tag are open and how old they are, from the tracker
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	opts := addScanFlags(fs)
+	bucketSpec := fs.String("buckets", defaultAgeBuckets, "Comma-separated age boundaries of the distribution, e.g. 1w,1m,6m")
+	asJSON := fs.Bool("json", false, "Print the statistics as JSON")
+	view := fs.String("view", "", "Only count the TODOs of a view defined in the config file")
+	filterExpr := fs.String("filter", "", "Only count the TODOs matching a filter expression")
+	snapshots := fs.String("snapshots", "", "Directory of the daemon's snapshots; adds weekly created and resolved counts")
+	weeks := fs.Int("weeks", 8, "Number of recent weeks of velocity to show")
+	fs.Parse(args)
+
+	buckets, err := parseAgeBuckets(*bucketSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --buckets: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	only, err := selectFilter(cfg, *view, *filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+	now := time.Now()
+	s := computeStats(applyFilter(t.Todos, only, now), buckets, now)
+	for i := range s.Tags {
+		s.Tags[i].Owner = ownerOf(cfg, s.Tags[i].Tag)
+	}
+	if *snapshots != "" {
+		if s.Velocity, err = computeVelocity(snapshotStore{dir: *snapshots}, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		if *weeks > 0 && len(s.Velocity) > *weeks {
+			s.Velocity = s.Velocity[len(s.Velocity)-*weeks:]
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		enc.Encode(s)
+		return
+	}
+	fmt.Print(formatStatsMarkdown(s))
+}
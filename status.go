@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	githubAPI = "https://api.github.com"
+	// statusContext names the commit status, apart from any check run, so
+	// branch protection can require it
+	statusContext = "collecttodo/policy"
+	// GitHub truncates longer status descriptions
+	statusDescriptionLimit = 140
+)
+
+// commitStatus sets the collecttodo/policy status of the commit being built
+// on GitHub, using the variables provided by GitHub Actions. The token comes
+// from GITHUB_TOKEN or GH_TOKEN and needs the statuses: write permission.
+type commitStatus struct {
+	apiURL string
+	repo   string
+	sha    string
+	token  string
+	runURL string
+	client *http.Client
+}
+
+func newCommitStatus() (*commitStatus, error) {
+	s := &commitStatus{
+		apiURL: firstNonEmpty(os.Getenv("GITHUB_API_URL"), githubAPI),
+		repo:   os.Getenv("GITHUB_REPOSITORY"),
+		sha:    firstNonEmpty(pullRequestHead(os.Getenv("GITHUB_EVENT_PATH")), os.Getenv("GITHUB_SHA")),
+		token:  firstNonEmpty(os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN")),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.repo == "" || s.sha == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY and GITHUB_SHA must be set")
+	}
+	if s.token == "" {
+		return nil, fmt.Errorf("set GITHUB_TOKEN or GH_TOKEN")
+	}
+	if server, run := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_RUN_ID"); server != "" && run != "" {
+		s.runURL = fmt.Sprintf("%s/%s/actions/runs/%s", server, s.repo, run)
+	}
+	return s, nil
+}
+
+// pullRequestHead returns the head commit of the pull request in a GitHub
+// event payload, or "". On pull requests GITHUB_SHA is a merge commit that
+// the pull request page does not show statuses of.
+func pullRequestHead(eventPath string) string {
+	if eventPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return ""
+	}
+	var event struct {
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return ""
+	}
+	return event.PullRequest.Head.SHA
+}
+
+// statusDescription summarizes the outcome in the status' one line
+func statusDescription(total int, violations []policyViolation) string {
+	if len(violations) == 0 {
+		return fmt.Sprintf("%d TODOs, all policies pass", total)
+	}
+	var policies []string
+	seen := make(map[string]bool)
+	for _, v := range violations {
+		if !seen[v.policy] {
+			seen[v.policy] = true
+			policies = append(policies, v.policy)
+		}
+	}
+	desc := fmt.Sprintf("%d policy violations: %s", len(violations), strings.Join(policies, ", "))
+	return truncate(desc, statusDescriptionLimit)
+}
+
+// Post sets the status to success when no policy failed, and to failure
+// otherwise
+func (s *commitStatus) Post(total int, violations []policyViolation) error {
+	state := "success"
+	if len(violations) > 0 {
+		state = "failure"
+	}
+	body := map[string]string{
+		"state":       state,
+		"context":     statusContext,
+		"description": statusDescription(total, violations),
+	}
+	if s.runURL != "" {
+		body["target_url"] = s.runURL
+	}
+	return sendJSON(s.client, http.MethodPost, fmt.Sprintf("%s/repos/%s/statuses/%s", s.apiURL, s.repo, s.sha), body, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	})
+}
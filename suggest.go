@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// tagHints lists, for the tags suggested to untagged TODOs, the words of a
+// description that point to them
+var tagHints = []struct {
+	tag   string
+	words []string
+}{
+	{"security", []string{"security", "auth", "password", "secret", "token", "xss", "csrf", "injection", "sanitize", "crypto", "permission"}},
+	{"perf", []string{"perf", "performance", "slow", "fast", "faster", "optimize", "cache", "latency", "memory", "allocation", "n+1"}},
+	{"bug", []string{"bug", "crash", "broken", "wrong", "incorrect", "race", "leak", "panic", "fails", "error"}},
+	{"test", []string{"test", "tests", "coverage", "flaky", "mock", "assert"}},
+	{"docs", []string{"doc", "docs", "document", "documentation", "readme", "comment", "explain"}},
+	{"refactor", []string{"refactor", "cleanup", "clean", "rename", "simplify", "duplicate", "duplicated", "extract", "split"}},
+	{"deprecated", []string{"deprecated", "legacy", "remove", "drop", "obsolete", "compat"}},
+}
+
+// pathHints maps path elements and file suffixes to the tag they suggest
+var pathHints = []struct {
+	match string
+	tag   string
+}{
+	{"_test.", "test"},
+	{"/test/", "test"},
+	{"/tests/", "test"},
+	{"/docs/", "docs"},
+	{".md", "docs"},
+	{"/migrations/", "db"},
+	{".sql", "db"},
+	{"/.github/", "ci"},
+	{"/security/", "security"},
+	{"/auth/", "security"},
+}
+
+// suggestTag guesses a tag for an untagged TODO from the words of its
+// description, and from its path when they do not decide. It returns "" when
+// nothing points to a tag.
+func suggestTag(description, path string) string {
+	words := descriptionWords(description)
+	best, bestScore := "", 0
+	for _, h := range tagHints {
+		score := 0
+		for _, w := range h.words {
+			if words[w] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = h.tag, score
+		}
+	}
+	if best != "" {
+		return best
+	}
+	slashed := "/" + strings.ToLower(filepath.ToSlash(path))
+	for _, h := range pathHints {
+		if strings.Contains(slashed, h.match) {
+			return h.tag
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSummaryItems bounds the items of a tag sent to the summarizer; counts
+// and ages always cover all of them
+const maxSummaryItems = 50
+
+// SummaryConfig names the command writing the executive summary of reports
+type SummaryConfig struct {
+	// Command reads the TODOs grouped by tag as JSON on standard input and
+	// prints a short summary in markdown
+	Command string `json:"command,omitempty"`
+}
+
+// tagDigest is what the summarizer is told about the TODOs of a tag
+type tagDigest struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+	// Ages are in days since the items were first seen
+	OldestAge int `json:"oldest_age"`
+	MedianAge int `json:"median_age"`
+	// Directories counts the items of the most common directories
+	Directories map[string]int `json:"directories"`
+	Items       []summaryItem  `json:"items"`
+}
+
+// summaryItem is one TODO as sent to the summarizer
+type summaryItem struct {
+	Description string `json:"description"`
+	File        string `json:"file"`
+	Age         int    `json:"age"`
+	Priority    string `json:"priority,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+}
+
+// Summarizer condenses the TODOs of every tag into a few sentences
+type Summarizer interface {
+	Summarize(tags []tagDigest) (string, error)
+}
+
+// commandSummarizer runs a shell command, such as a script calling a
+// language model, to write the summary
+type commandSummarizer struct {
+	command string
+	timeout time.Duration
+}
+
+func (c commandSummarizer) Summarize(tags []tagDigest) (string, error) {
+	input, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Stdin = strings.NewReader(string(input))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("summary command: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// digestTags groups the items by tag, most items first, with their ages and
+// where they are
+func digestTags(todos []TodoItem, now time.Time) []tagDigest {
+	byTag := make(map[string][]TodoItem)
+	for _, t := range todos {
+		byTag[t.Tag] = append(byTag[t.Tag], t)
+	}
+	digests := make([]tagDigest, 0, len(byTag))
+	for tag, items := range byTag {
+		sortByUrgency(items)
+		d := tagDigest{Tag: tag, Count: len(items), Directories: make(map[string]int)}
+		ages := make([]int, len(items))
+		dirs := make(map[string]int)
+		for i, t := range items {
+			ages[i] = ageInDays(t, now)
+			dirs[path.Dir(repoPath(t.File))]++
+			if i < maxSummaryItems {
+				d.Items = append(d.Items, summaryItem{t.Description, repoPath(t.File), ages[i], t.Priority, t.Severity})
+			}
+		}
+		sort.Ints(ages)
+		d.OldestAge, d.MedianAge = ages[len(ages)-1], ages[len(ages)/2]
+		names := make([]string, 0, len(dirs))
+		for dir := range dirs {
+			names = append(names, dir)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if dirs[names[i]] != dirs[names[j]] {
+				return dirs[names[i]] > dirs[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		for i, dir := range names {
+			if i == 5 {
+				break
+			}
+			d.Directories[dir] = dirs[dir]
+		}
+		digests = append(digests, d)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		if digests[i].Count != digests[j].Count {
+			return digests[i].Count > digests[j].Count
+		}
+		return digests[i].Tag < digests[j].Tag
+	})
+	return digests
+}
+
+// summarizeTodos returns the executive summary of the items, or "" when
+// there are none
+func summarizeTodos(todos []TodoItem, s Summarizer, now time.Time) (string, error) {
+	if len(todos) == 0 {
+		return "", nil
+	}
+	return s.Summarize(digestTags(todos, now))
+}
+
+func formatExecutiveMarkdown(summary string) string {
+	if summary == "" {
+		return ""
+	}
+	return "# Executive Summary\n\n" + summary + "\n\n"
+}
+
+// summarizerFor returns the summarizer of the configuration, or nil when
+// summaries are disabled
+func summarizerFor(c Config) Summarizer {
+	if c.Summary.Command == "" {
+		return nil
+	}
+	return commandSummarizer{command: c.Summary.Command, timeout: 2 * time.Minute}
+}
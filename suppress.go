@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SuppressRule keeps the items it matches out of reports and policies. Every
+// field that is set must match: File is a glob such as third_party/**, Tag
+// the exact tag, and Description a substring, or a regular expression when
+// it starts with ~.
+type SuppressRule struct {
+	File        string `json:"file,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Reason documents why the items are suppressed
+	Reason string `json:"reason,omitempty"`
+}
+
+// compile returns a function reporting whether an item matches the rule
+func (r SuppressRule) compile() (func(TodoItem) bool, error) {
+	if r.File == "" && r.Tag == "" && r.Description == "" {
+		return nil, fmt.Errorf("set at least one of file, tag and description")
+	}
+	var file, description *regexp.Regexp
+	var err error
+	if r.File != "" {
+		if file, err = globRegexp(r.File); err != nil {
+			return nil, fmt.Errorf("file: %v", err)
+		}
+	}
+	if pattern, ok := strings.CutPrefix(r.Description, "~"); ok {
+		if description, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("description: %v", err)
+		}
+	}
+	return func(t TodoItem) bool {
+		switch {
+		case file != nil && !file.MatchString(repoPath(t.File)):
+			return false
+		case r.Tag != "" && t.Tag != r.Tag:
+			return false
+		case description != nil:
+			return description.MatchString(t.Description)
+		default:
+			return strings.Contains(t.Description, r.Description)
+		}
+	}, nil
+}
+
+// suppressionFilter returns a filter matching the items of any suppression
+// rule, or nil without rules
+func suppressionFilter(rules []SuppressRule) (filter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	var matchers []func(TodoItem) bool
+	for i, r := range rules {
+		m, err := r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("suppress[%d]: %v", i, err)
+		}
+		matchers = append(matchers, m)
+	}
+	return func(t TodoItem, _ time.Time) bool {
+		for _, m := range matchers {
+			if m(t) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// formatSuppressedMarkdown notes how many items the suppression rules kept
+// out of the summary
+func formatSuppressedMarkdown(count int) string {
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_%d TODOs matching the suppression rules are not listed._\n\n", count)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is what a --template renders; README documents every field
+type templateData struct {
+	// Generated is the time of the run, RFC 3339, and Now its day
+	Generated string
+	Now       string
+	Roots     []string
+	// Items are the open TODOs, most urgent first
+	Items    []TodoItem
+	New      []TodoItem
+	Resolved []TodoItem
+	// Groups are the sections of the summary, by tag or by assignee
+	Groups []templateGroup
+	Stats  stats
+	// Skipped are the scan warnings: files skipped or not read completely
+	Skipped    []ScanWarning
+	Violations []jsonViolation
+}
+
+// templateGroup is a tag, or an assignee, and its TODOs
+type templateGroup struct {
+	// Name is "" for untagged or unassigned TODOs
+	Name string
+	// Owner is the team owning a tag, if any
+	Owner string
+	Items []TodoItem
+}
+
+// templateFuncs are the functions available to templates besides the
+// text/template built-ins
+func templateFuncs(r Report, now time.Time) template.FuncMap {
+	return template.FuncMap{
+		"join":  strings.Join,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"age":   func(t TodoItem) int { return ageInDays(t, now) },
+		"link": func(t TodoItem) string {
+			if t.Permalink != "" || r.Link == nil {
+				return t.Permalink
+			}
+			return r.Link(t.File, t.Line)
+		},
+	}
+}
+
+// templateFormat returns a renderer executing the text/template at path,
+// for --template
+func templateFormat(path string) (outputFormat, error) {
+	// Functions are bound to each report, so parsing only needs their names
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs(Report{}, time.Time{})).ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return func(r Report) (string, error) {
+		now, err := time.Parse("2006-01-02", r.Now)
+		if err != nil {
+			now = time.Now()
+		}
+		var b strings.Builder
+		if err := tmpl.Funcs(templateFuncs(r, now)).Execute(&b, newTemplateData(r, now)); err != nil {
+			return "", fmt.Errorf("rendering %s: %w", path, err)
+		}
+		return b.String(), nil
+	}, nil
+}
+
+func newTemplateData(r Report, now time.Time) templateData {
+	items := append([]TodoItem(nil), r.Todos...)
+	sortByUrgency(items)
+	buckets, _ := parseAgeBuckets(defaultAgeBuckets)
+	data := templateData{
+		Generated:  r.Generated,
+		Now:        r.Now,
+		Roots:      r.Roots,
+		Items:      items,
+		New:        r.New,
+		Resolved:   r.Resolved,
+		Stats:      computeStats(r.Todos, buckets, now),
+		Skipped:    r.Warnings,
+		Violations: []jsonViolation{},
+	}
+	for _, v := range r.Violations {
+		data.Violations = append(data.Violations, jsonViolation{Policy: v.policy, Message: v.message})
+	}
+	groups := make(map[string][]TodoItem)
+	for _, t := range items {
+		names := []string{t.Tag}
+		if r.GroupBy == "assignee" {
+			names = t.Assignees
+			if len(names) == 0 {
+				names = []string{""}
+			}
+		}
+		for _, name := range names {
+			groups[name] = append(groups[name], t)
+		}
+	}
+	for name, todos := range groups {
+		g := templateGroup{Name: name, Items: todos}
+		if r.GroupBy != "assignee" {
+			g.Owner = r.Owners[name].Team
+		}
+		data.Groups = append(data.Groups, g)
+	}
+	// Named groups come first, in order, and the unnamed one last
+	sort.Slice(data.Groups, func(i, j int) bool {
+		a, b := data.Groups[i].Name, data.Groups[j].Name
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		return a < b
+	})
+	return data
+}
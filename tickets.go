@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// TicketRecognizer finds references to the tickets of one tracker in
+// descriptions and normalizes them, so that jira-12 and JIRA-12 are the same
+// ticket
+type TicketRecognizer struct {
+	// Pattern finds a reference, e.g. (?i)\bjira-(\d+)
+	Pattern string `json:"pattern"`
+	// Ticket is the normalized ticket, with $1 for the first group of the
+	// pattern and so on, e.g. JIRA-$1; it is the whole match when empty
+	Ticket string `json:"ticket,omitempty"`
+	// URL links the ticket, expanded like Ticket, e.g.
+	// https://example.atlassian.net/browse/JIRA-$1
+	URL string `json:"url,omitempty"`
+}
+
+// defaultTicketRecognizers recognize tracker keys such as JIRA-456 when no
+// recognizers are configured
+var defaultTicketRecognizers = []TicketRecognizer{{Pattern: `\b[A-Z][A-Z0-9]+-\d+\b`}}
+
+// ticketRecognizer is a compiled TicketRecognizer
+type ticketRecognizer struct {
+	re          *regexp.Regexp
+	ticket, url string
+}
+
+// compileTicketRecognizers compiles the configured recognizers, or the
+// default ones when none are configured
+func compileTicketRecognizers(configured []TicketRecognizer) ([]ticketRecognizer, error) {
+	if len(configured) == 0 {
+		configured = defaultTicketRecognizers
+	}
+	var compiled []ticketRecognizer
+	for i, r := range configured {
+		re, err := compilePattern(r.Pattern)
+		if err != nil || r.Pattern == "" {
+			return nil, fmt.Errorf("tickets[%d]: %q is not a valid regular expression: %v", i, r.Pattern, err)
+		}
+		ticket := r.Ticket
+		if ticket == "" {
+			ticket = "$0"
+		}
+		compiled = append(compiled, ticketRecognizer{re: re, ticket: ticket, url: r.URL})
+	}
+	return compiled, nil
+}
+
+// findTicket returns the first ticket referenced in a description, normalized,
+// and its URL; the earliest reference wins, then the first recognizer
+func findTicket(recognizers []ticketRecognizer, description string) (string, string) {
+	var best *ticketRecognizer
+	var bestMatch []int
+	for i := range recognizers {
+		m := recognizers[i].re.FindStringSubmatchIndex(description)
+		if m != nil && (bestMatch == nil || m[0] < bestMatch[0]) {
+			best, bestMatch = &recognizers[i], m
+		}
+	}
+	if best == nil {
+		return "", ""
+	}
+	expand := func(template string) string {
+		if template == "" {
+			return ""
+		}
+		return string(best.re.ExpandString(nil, template, description, bestMatch))
+	}
+	return expand(best.ticket), expand(best.url)
+}
+
+// formatOrphansMarkdown notes how many items refer to no ticket
+func formatOrphansMarkdown(todos []TodoItem) string {
+	orphans := 0
+	for _, t := range todos {
+		if t.Ticket == "" {
+			orphans++
+		}
+	}
+	if orphans == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_%d of %d TODOs have no ticket._\n\n", orphans, len(todos))
+}
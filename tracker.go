@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// suppressTodo moves the tracked item with the given ID, or ID prefix, to
+// the suppressed list, so that later scans leave it out
+func suppressTodo(t *TodoTracker, id string) (TodoItem, error) {
+	i, err := findTodo(t.Todos, id)
+	if err != nil {
+		return TodoItem{}, err
+	}
+	item := t.Todos[i]
+	t.Todos = append(t.Todos[:i], t.Todos[i+1:]...)
+	t.Suppressed = append(t.Suppressed, item)
+	return item, nil
+}
+
+// restoreTodo moves a suppressed item back to the tracked ones, with its
+// first-seen date
+func restoreTodo(t *TodoTracker, id string) (TodoItem, error) {
+	i, err := findTodo(t.Suppressed, id)
+	if err != nil {
+		return TodoItem{}, err
+	}
+	item := t.Suppressed[i]
+	t.Suppressed = append(t.Suppressed[:i], t.Suppressed[i+1:]...)
+	t.Todos = append(t.Todos, item)
+	return item, nil
+}
+
+// findTodo returns the index of the only item whose ID starts with id
+func findTodo(todos []TodoItem, id string) (int, error) {
+	found := -1
+	for i, t := range todos {
+		if t.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(t.ID, id) {
+			if found >= 0 {
+				return 0, fmt.Errorf("%q matches several items; give more of the ID", id)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("no item with ID %q", id)
+	}
+	return found, nil
+}
+
+// withoutSuppressed drops the found items that were suppressed
+func withoutSuppressed(found, suppressed []TodoItem) []TodoItem {
+	if len(suppressed) == 0 {
+		return found
+	}
+	ids := make(map[string]bool)
+	for _, t := range suppressed {
+		ids[t.ID] = true
+	}
+	var kept []TodoItem
+	for _, t := range found {
+		if !ids[t.ID] {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// runTracker implements the tracker command: rm suppresses false positives,
+// restore brings them back, and suppressed lists them
+func runTracker(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: collecttodo tracker rm|restore <id>... | tracker suppressed")
+		os.Exit(2)
+	}
+	if len(args) == 0 {
+		usage()
+	}
+	fs := flag.NewFlagSet("tracker "+args[0], flag.ExitOnError)
+	opts := addScanFlags(fs)
+	ids := parseInterspersed(fs, args[1:])
+
+	cfg, err := opts.Config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	t, err := loadTracker(cfg.Outputs.Tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tracker: %v\n", err)
+		os.Exit(1)
+	}
+
+	var move func(*TodoTracker, string) (TodoItem, error)
+	switch args[0] {
+	case "suppressed":
+		for _, item := range t.Suppressed {
+			fmt.Printf("%s  %s %s (%s:%d)\n", item.ID, item.Label(), item.Description, repoPath(item.File), item.Line)
+		}
+		return
+	case "rm":
+		move = suppressTodo
+	case "restore":
+		move = restoreTodo
+	default:
+		usage()
+	}
+	if len(ids) == 0 {
+		usage()
+	}
+	if err := refuseWrite(cfg, cfg.Outputs.Tracker); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, id := range ids {
+		item, err := move(&t, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		verb := "Suppressed"
+		if args[0] == "restore" {
+			verb = "Restored"
+		}
+		fmt.Printf("%s %s %s %s (%s:%d)\n", verb, item.ID, item.Label(), item.Description, repoPath(item.File), item.Line)
+	}
+	if err := saveTracker(cfg.Outputs.Tracker, t); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tracker: %v\n", err)
+		os.Exit(1)
+	}
+}
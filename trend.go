@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// weekVelocity is how the open TODOs changed during one ISO week
+type weekVelocity struct {
+	Week string `json:"week"`
+	// Open is the number of open TODOs at the last snapshot of the week
+	Open     int `json:"open"`
+	Created  int `json:"created"`
+	Resolved int `json:"resolved"`
+	// Net is Created minus Resolved; negative means the debt shrank
+	Net int `json:"net"`
+}
+
+// snapshotTags is the tag of every TODO open in a snapshot, by ID
+type snapshotTags struct {
+	date string
+	tags map[string]string
+}
+
+// loadSnapshotTags reads the snapshots of a namespace, oldest first
+func loadSnapshotTags(store snapshotStore, namespace string) ([]snapshotTags, error) {
+	dates, err := store.Dates(namespace)
+	if err != nil {
+		return nil, err
+	}
+	snaps := make([]snapshotTags, 0, len(dates))
+	for _, date := range dates {
+		snap, _, err := store.At(namespace, date)
+		if err != nil {
+			return nil, err
+		}
+		tags := make(map[string]string, len(snap.Todos))
+		for _, t := range snap.Todos {
+			tags[t.ID] = t.Tag
+		}
+		snaps = append(snaps, snapshotTags{date: date, tags: tags})
+	}
+	return snaps, nil
+}
+
+// computeVelocity compares consecutive snapshots of a namespace and counts
+// the TODOs created and resolved in every week. The first snapshot is the
+// baseline, so its own TODOs do not count as created.
+func computeVelocity(store snapshotStore, namespace string) ([]weekVelocity, error) {
+	snaps, err := loadSnapshotTags(store, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return weeklyVelocity(snaps, func(string) bool { return true }), nil
+}
+
+// weeklyVelocity computes the velocity of the TODOs whose tag is kept
+func weeklyVelocity(snaps []snapshotTags, keep func(tag string) bool) []weekVelocity {
+	var weeks []weekVelocity
+	var previous map[string]bool
+	for _, snap := range snaps {
+		current := make(map[string]bool, len(snap.tags))
+		for id, tag := range snap.tags {
+			if keep(tag) {
+				current[id] = true
+			}
+		}
+		day, _ := time.Parse("2006-01-02", snap.date)
+		year, week := day.ISOWeek()
+		label := fmt.Sprintf("%d-W%02d", year, week)
+		if len(weeks) == 0 || weeks[len(weeks)-1].Week != label {
+			weeks = append(weeks, weekVelocity{Week: label})
+		}
+		w := &weeks[len(weeks)-1]
+		w.Open = len(current)
+		if previous != nil {
+			for id := range current {
+				if !previous[id] {
+					w.Created++
+				}
+			}
+			for id := range previous {
+				if !current[id] {
+					w.Resolved++
+				}
+			}
+			w.Net = w.Created - w.Resolved
+		}
+		previous = current
+	}
+	return weeks
+}
+
+// tagForecast projects when the open TODOs of a tag would reach zero if the
+// recent net change per week went on
+type tagForecast struct {
+	Tag  string `json:"tag"`
+	Open int    `json:"open"`
+	// NetPerWeek is the average net change over the window of weeks
+	NetPerWeek float64 `json:"net_per_week"`
+	// WeeksToZero and ZeroBy are only set when the backlog is shrinking
+	WeeksToZero float64 `json:"weeks_to_zero,omitempty"`
+	ZeroBy      string  `json:"zero_by,omitempty"`
+}
+
+// forecastPayoff projects every tag of the latest snapshot, and the whole
+// backlog as tag "all", from the average net change of the last window weeks
+func forecastPayoff(snaps []snapshotTags, window int) []tagForecast {
+	if len(snaps) == 0 {
+		return nil
+	}
+	latest := snaps[len(snaps)-1]
+	last, _ := time.Parse("2006-01-02", latest.date)
+	project := func(name string, keep func(tag string) bool) tagForecast {
+		weeks := weeklyVelocity(snaps, keep)
+		if window > 0 && len(weeks) > window {
+			weeks = weeks[len(weeks)-window:]
+		}
+		f := tagForecast{Tag: name, Open: weeks[len(weeks)-1].Open}
+		net := 0
+		for _, w := range weeks {
+			net += w.Net
+		}
+		f.NetPerWeek = float64(net) / float64(len(weeks))
+		if f.NetPerWeek < 0 && f.Open > 0 {
+			f.WeeksToZero = float64(f.Open) / -f.NetPerWeek
+			f.ZeroBy = last.AddDate(0, 0, int(math.Ceil(f.WeeksToZero*7))).Format("2006-01-02")
+		}
+		return f
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range latest.tags {
+		seen[tag] = true
+	}
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var forecasts []tagForecast
+	for _, tag := range tags {
+		tag := tag
+		forecasts = append(forecasts, project(tag, func(t string) bool { return t == tag }))
+	}
+	return append(forecasts, project("all", func(string) bool { return true }))
+}
+
+func formatForecastMarkdown(forecasts []tagForecast, window int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n## Forecast\n\nAt the average pace of the last %d weeks:\n\n", window))
+	b.WriteString("| Tag | Open | Net per week | Zero by |\n")
+	b.WriteString("| --- | ---: | ---: | --- |\n")
+	for _, f := range forecasts {
+		zero := "not at this pace"
+		if f.ZeroBy != "" {
+			zero = fmt.Sprintf("%s (~%.0f weeks)", f.ZeroBy, math.Ceil(f.WeeksToZero))
+		}
+		b.WriteString(fmt.Sprintf("| %s | %d | %+.1f | %s |\n", f.Tag, f.Open, f.NetPerWeek, zero))
+	}
+	return b.String()
+}
+
+func formatVelocityMarkdown(weeks []weekVelocity) string {
+	var b strings.Builder
+	b.WriteString("| Week | Open | Created | Resolved | Net |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+	for _, w := range weeks {
+		b.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %+d |\n", w.Week, w.Open, w.Created, w.Resolved, w.Net))
+	}
+	return b.String()
+}
+
+func formatVelocityCSV(weeks []weekVelocity) string {
+	var b strings.Builder
+	b.WriteString("week,open,created,resolved,net\n")
+	for _, w := range weeks {
+		b.WriteString(fmt.Sprintf("%s,%d,%d,%d,%d\n", w.Week, w.Open, w.Created, w.Resolved, w.Net))
+	}
+	return b.String()
+}
+
+// runTrend implements the trend command, which shows from the daemon's
+// snapshots whether TODOs are resolved as fast as they are created
+func runTrend(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	dir := fs.String("snapshots", "snapshots", "Directory of the daemon's snapshots")
+	weeksFlag := fs.Int("weeks", 0, "Only show the last n weeks (default: all)")
+	window := fs.Int("window", 4, "Number of recent weeks whose average pace the forecast assumes")
+	format := fs.String("format", "markdown", "Output format: markdown, json or csv (for charting)")
+	fs.Parse(args)
+
+	snaps, err := loadSnapshotTags(snapshotStore{dir: *dir}, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading snapshots: %v\n", err)
+		os.Exit(1)
+	}
+	weeks := weeklyVelocity(snaps, func(string) bool { return true })
+	forecasts := forecastPayoff(snaps, *window)
+	if *weeksFlag > 0 && len(weeks) > *weeksFlag {
+		weeks = weeks[len(weeks)-*weeksFlag:]
+	}
+
+	switch *format {
+	case "markdown":
+		fmt.Print("# TODO Trend\n\n" + formatVelocityMarkdown(weeks))
+		if len(forecasts) > 0 {
+			// The pace is averaged over the weeks there are
+			n := *window
+			if n <= 0 || n > len(weeks) {
+				n = len(weeks)
+			}
+			fmt.Print(formatForecastMarkdown(forecasts, n))
+		}
+	case "csv":
+		fmt.Print(formatVelocityCSV(weeks))
+	case "json":
+		if weeks == nil {
+			weeks = []weekVelocity{}
+		}
+		if forecasts == nil {
+			forecasts = []tagForecast{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Weeks    []weekVelocity `json:"weeks"`
+			Forecast []tagForecast  `json:"forecast"`
+		}{weeks, forecasts})
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (supported: markdown, json, csv)\n", *format)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScanWarning is a problem that did not stop the scan but may leave TODOs
+// out of the results; every output format lists them in an appendix
+type ScanWarning struct {
+	Path string `json:"path"`
+	// Kind is one of the warning kinds below
+	Kind string `json:"kind"`
+	// Message is a sentence naming the path
+	Message string `json:"message"`
+}
+
+// Kinds of scan warnings
+const (
+	warnTooLarge    = "too-large"
+	warnUnreadable  = "unreadable"
+	warnInvalidUTF8 = "invalid-utf8"
+	warnLongLines   = "long-lines"
+	warnSymlink     = "symlink"
+	warnMatchBudget = "match-budget"
+	warnTruncated   = "truncated"
+	warnByteBudget  = "byte-budget"
+)
+
+func newWarning(path, kind, format string, args ...interface{}) ScanWarning {
+	return ScanWarning{Path: repoPath(path), Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (w ScanWarning) String() string {
+	return w.Message
+}
+
+// formatWarningsMarkdown renders the warnings as the appendix of the
+// markdown summary
+func formatWarningsMarkdown(warnings []ScanWarning) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n# Scan Warnings\n\n")
+	for _, w := range warnings {
+		b.WriteString(fmt.Sprintf("- `%s` %s\n", w.Kind, w.Message))
+	}
+	b.WriteString("\n")
+	return b.String()
+}